@@ -22,7 +22,10 @@ type Config struct {
 	LegacyLogPaths    bool                   `yaml:"legacyLogPaths"`
 	SplitLogging      bool                   `yaml:"splitLogging"`
 	AdditionalFields  map[string]interface{} `yaml:"additionalFields"`
-	Metrics           *MetricsConfig
+	// SchemaFile is the path to a file listing the types of each dataset's
+	// fields, which events are converted to before they're sent.
+	SchemaFile string `yaml:"schemaFile"`
+	Metrics    *MetricsConfig
 }
 
 type WatcherConfig struct {
@@ -43,6 +46,9 @@ type WatcherConfig struct {
 type ParserConfig struct {
 	Name    string
 	Options map[string]interface{}
+	// Parsers is the ordered list of parsers to try for the "fallback"
+	// parser. It's filled in when the parser is configured as a YAML list.
+	Parsers []*ParserConfig
 }
 
 type MetricsConfig struct {
@@ -69,19 +75,45 @@ func (p *ParserConfig) UnmarshalYAML(unmarshal func(interface{}) error) error {
 		p.Name = name
 		return nil
 	}
+	var parsers []*ParserConfig
+	if err := unmarshal(&parsers); err == nil {
+		p.Name = "fallback"
+		p.Parsers = parsers
+		return nil
+	}
 	aux := &struct {
 		Name    string
 		Options map[string]interface{}
+		Parsers []*ParserConfig
 	}{}
 	err := unmarshal(&aux)
 	if err == nil {
 		p.Name = aux.Name
 		p.Options = aux.Options
+		p.Parsers = aux.Parsers
 		return nil
 	}
 	return err
 }
 
+// NewParserConfig builds a ParserConfig out of a value that's already been
+// unmarshalled from YAML, such as a processor option. It accepts the same
+// forms as a watcher's `parser` key: a name, a name with options, or a list.
+func NewParserConfig(raw interface{}) (*ParserConfig, error) {
+	contents, err := yaml.Marshal(raw)
+	if err != nil {
+		return nil, err
+	}
+	p := &ParserConfig{}
+	if err = yaml.Unmarshal(contents, p); err != nil {
+		return nil, err
+	}
+	if p.Name == "" {
+		return nil, fmt.Errorf("missing parser name")
+	}
+	return p, nil
+}
+
 func ReadFromFile(filePath string) (*Config, error) {
 	contents, err := ioutil.ReadFile(filePath)
 	if err != nil {
@@ -14,7 +14,10 @@ func TestParsing(t *testing.T) {
 	}{
 		{"basic.yaml", true},
 		{"parser_with_options.yaml", true},
-		{"unknown_parsers.yaml", false},
+		// Parser names are checked when the watcher is set up, so a list of
+		// unknown parsers is still syntactically valid here.
+		{"unknown_parsers.yaml", true},
+		{"parser_fallbacks.yaml", true},
 		{"labelselector-and-paths.yaml", false},
 		{"paths-only.yaml", true},
 	}
@@ -51,3 +54,22 @@ func TestRegexExpressionsArrayParsing(t *testing.T) {
 	assert.Equal(t, "regex", c.Watchers[1].Parser.Name)
 	assert.Equal(t, map[string]interface{}{"expressions": []interface{}{"foo", "bar"}}, c.Watchers[1].Parser.Options)
 }
+
+func TestParserFallbacksParsing(t *testing.T) {
+	path, _ := filepath.Abs(filepath.Join("testdata", "parser_fallbacks.yaml"))
+	c, err := ReadFromFile(path)
+	assert.NoError(t, err)
+
+	p := c.Watchers[0].Parser
+	assert.Equal(t, "fallback", p.Name)
+	assert.Equal(t, 3, len(p.Parsers))
+	assert.Equal(t, "json", p.Parsers[0].Name)
+	assert.Equal(t, "keyval", p.Parsers[1].Name)
+	assert.Equal(t, map[string]interface{}{"prefixRegex": "(?P<timestamp>[0-9TZ:.-]+) "}, p.Parsers[1].Options)
+	assert.Equal(t, "nop", p.Parsers[2].Name)
+
+	p = c.Watchers[1].Parser
+	assert.Equal(t, "fallback", p.Name)
+	assert.Equal(t, map[string]interface{}{"field": "format"}, p.Options)
+	assert.Equal(t, 2, len(p.Parsers))
+}
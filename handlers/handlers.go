@@ -7,8 +7,11 @@ package handlers
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
 	"github.com/honeycombio/honeycomb-kubernetes-agent/parsers"
 	"github.com/honeycombio/honeycomb-kubernetes-agent/processors"
 	"github.com/honeycombio/honeycomb-kubernetes-agent/transmission"
@@ -24,6 +27,15 @@ type LineHandlerFactory interface {
 	New(path string) LineHandler
 }
 
+// BackgroundLineHandlerFactory is implemented by line handler factories with
+// processors that do work in the background. The watcher using the factory
+// starts and stops it.
+type BackgroundLineHandlerFactory interface {
+	LineHandlerFactory
+	Start()
+	Stop()
+}
+
 type LineHandlerFactoryImpl struct {
 	config        *config.WatcherConfig
 	unwrapper     unwrappers.Unwrapper
@@ -74,6 +86,23 @@ func NewLineHandlerFactoryFromConfig(
 	return ret, nil
 }
 
+// Start starts any processors that work in the background.
+func (hf *LineHandlerFactoryImpl) Start() {
+	for _, p := range hf.processors {
+		if bp, ok := p.(processors.BackgroundProcessor); ok {
+			bp.Start()
+		}
+	}
+}
+
+func (hf *LineHandlerFactoryImpl) Stop() {
+	for _, p := range hf.processors {
+		if bp, ok := p.(processors.BackgroundProcessor); ok {
+			bp.Stop()
+		}
+	}
+}
+
 func (hf *LineHandlerFactoryImpl) New(path string) LineHandler {
 	logrus.WithFields(logrus.Fields{
 		"path":   path,
@@ -97,20 +126,65 @@ type LineHandlerImpl struct {
 	parser      parsers.Parser
 	processors  []processors.Processor
 	transmitter transmission.Transmitter
+
+	// Guards the parser and the Go panic in progress, which is sent from a
+	// timer if the trace isn't ended by another line. Parsers that hold a
+	// record back until the next line are flushed from a timer too.
+	mu         sync.Mutex
+	panic      *goPanic
+	panicTimer *time.Timer
+	flushTimer *time.Timer
+	flushAt    time.Time
+	lastEvent  *event.Event
 }
 
+// lineParser passes lines through the unwrapper unparsed, so that the handler
+// can look for Go panics before parsing them.
+type lineParser struct{}
+
+func (lineParser) Parse(line string) (map[string]interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+// How long to wait for more lines before sending a record that the parser is
+// holding back.
+var parserFlushTimeout = 2 * time.Second
+
 func (h *LineHandlerImpl) Handle(rawLine string) {
-	event, err := h.unwrapper.Unwrap(rawLine, h.parser)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	event, err := h.unwrapper.Unwrap(rawLine, lineParser{})
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to parse line")
+		return
+	}
+	if h.handlePanicLine(event) {
+		return
+	}
+	h.parseAndSend(event)
+}
+
+func (h *LineHandlerImpl) parseAndSend(event *event.Event) {
+	data, err := h.parser.Parse(event.RawMessage)
+	if fp, ok := h.parser.(parsers.FlushableParser); ok && parsers.IsStateful(fp) && fp.Holding() {
+		h.scheduleFlush(event)
+	}
 	if err != nil {
 		logrus.WithError(err).Debug("Failed to parse line")
 		return
 	}
-	if event == nil {
+	if data == nil {
 		// No error, but no event produced (e.g., the line produced
 		// something the parser thinks is incomplete).
 		// TODO: is there a better way to handle this?
 		return
 	}
+	event.Data = data
+	h.send(event)
+}
+
+func (h *LineHandlerImpl) send(event *event.Event) {
 	event.Dataset = h.config.Dataset
 	event.Path = h.path
 	for _, p := range h.processors {
@@ -123,3 +197,85 @@ func (h *LineHandlerImpl) Handle(rawLine string) {
 	logrus.WithField("parsed", event).Trace("Sending line")
 	h.transmitter.Send(event)
 }
+
+// scheduleFlush sends the record the parser is holding back, if it's still
+// holding it once the file has been quiet for a while. There might never be
+// another line to end it, e.g. when a service logs one error with a stack
+// trace and then nothing else.
+func (h *LineHandlerImpl) scheduleFlush(last *event.Event) {
+	h.lastEvent = last
+	h.flushAt = time.Now().Add(parserFlushTimeout)
+	if h.flushTimer == nil {
+		h.flushTimer = time.AfterFunc(parserFlushTimeout, h.flushParser)
+		return
+	}
+	h.flushTimer.Reset(parserFlushTimeout)
+}
+
+func (h *LineHandlerImpl) flushParser() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	// The timer may have fired while a line was being handled, pushing the
+	// flush back
+	if wait := time.Until(h.flushAt); wait > 0 {
+		h.flushTimer.Reset(wait)
+		return
+	}
+	fp := h.parser.(parsers.FlushableParser)
+	for data := fp.Flush(); data != nil; data = fp.Flush() {
+		// The event for the last line may have been sent already, with
+		// the record before this one
+		h.send(&event.Event{
+			Data:       data,
+			Timestamp:  h.lastEvent.Timestamp,
+			RawMessage: h.lastEvent.RawMessage,
+		})
+	}
+}
+
+// handlePanicLine returns true if the line is part of a Go panic trace, and
+// so shouldn't be parsed yet.
+func (h *LineHandlerImpl) handlePanicLine(event *event.Event) bool {
+	if h.panic == nil {
+		if !goPanicStart.MatchString(event.RawMessage) {
+			return false
+		}
+		h.panic = &goPanic{}
+	} else if !h.panic.accepts(event.RawMessage) {
+		h.finishPanic()
+		// The line could start another panic
+		return h.handlePanicLine(event)
+	}
+
+	h.panic.events = append(h.panic.events, event)
+	if h.panicTimer != nil {
+		h.panicTimer.Stop()
+	}
+	p := h.panic
+	h.panicTimer = time.AfterFunc(panicFlushTimeout, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		if h.panic == p {
+			h.finishPanic()
+		}
+	})
+	return true
+}
+
+// finishPanic sends the panic in progress. If no goroutine trace followed the
+// panic message, it wasn't a Go panic, so its lines are parsed as usual.
+func (h *LineHandlerImpl) finishPanic() {
+	p := h.panic
+	h.panic = nil
+	if h.panicTimer != nil {
+		h.panicTimer.Stop()
+		h.panicTimer = nil
+	}
+	if p.sawGoroutine {
+		h.send(p.event())
+		return
+	}
+	for _, event := range p.events {
+		h.parseAndSend(event)
+	}
+}
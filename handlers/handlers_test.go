@@ -7,6 +7,7 @@ package handlers
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -85,6 +86,8 @@ func TestInvalidConfigurations(t *testing.T) {
 		{"parser: json", "Missing dataset in configuration"},
 		{"dataset: kubernetestest", "No parser specified"},
 		{"parser: watparser\ndataset: kubernetestest", "Error setting up parser: Unknown parser type watparser"},
+		{"parser: [json, watparser]\ndataset: kubernetestest", "Error setting up parser: Error setting up fallback parser watparser: Unknown parser type watparser"},
+		{"parser: {name: fallback}\ndataset: kubernetestest", "Error setting up parser: fallback parser specified but no parsers defined"},
 	}
 
 	for _, tc := range testcases {
@@ -253,6 +256,91 @@ func TestNginxParsing(t *testing.T) {
 	}
 }
 
+func TestFallbackParsing(t *testing.T) {
+	tc := testCase{
+		config: `
+---
+dataset: kubernetestest
+parser:
+  - json
+  - glog
+  - nop
+`,
+		lines: []string{
+			`{"status": 200}`,
+			`I0720 00:23:31.949027       5 trace.go:61] Trace started`,
+			`Starting server on :8080`,
+		},
+		output: []event.Event{
+			{
+				Data: map[string]interface{}{
+					"status":      float64(200),
+					"meta.parser": "json",
+				},
+				Dataset:    "kubernetestest",
+				Path:       "/tmp/testpath",
+				RawMessage: `{"status": 200}`,
+			},
+			{
+				Data: map[string]interface{}{
+					"level":          "info",
+					"threadid":       "5",
+					"filename":       "trace.go",
+					"lineno":         "61",
+					"message":        "Trace started",
+					"glog_timestamp": time.Date(time.Now().Year(), 7, 20, 0, 23, 31, 949027000, time.UTC),
+					"meta.parser":    "glog",
+				},
+				Dataset:    "kubernetestest",
+				Path:       "/tmp/testpath",
+				RawMessage: `I0720 00:23:31.949027       5 trace.go:61] Trace started`,
+			},
+			{
+				Data: map[string]interface{}{
+					"log":         "Starting server on :8080",
+					"meta.parser": "nop",
+				},
+				Dataset:    "kubernetestest",
+				Path:       "/tmp/testpath",
+				RawMessage: `Starting server on :8080`,
+			},
+		},
+	}
+	tc.check(t)
+
+	// Without a catch-all, lines no parser understands are dropped, and the
+	// field recording the matched parser can be renamed.
+	tc = testCase{
+		config: `
+---
+dataset: kubernetestest
+parser:
+  name: fallback
+  options:
+    field: format
+  parsers:
+    - json
+    - glog
+`,
+		lines: []string{
+			`Starting server on :8080`,
+			`{"status": 200}`,
+		},
+		output: []event.Event{
+			{
+				Data: map[string]interface{}{
+					"status": float64(200),
+					"format": "json",
+				},
+				Dataset:    "kubernetestest",
+				Path:       "/tmp/testpath",
+				RawMessage: `{"status": 200}`,
+			},
+		},
+	}
+	tc.check(t)
+}
+
 func TestGlogParsing(t *testing.T) {
 	mt := &MockTransmitter{}
 	cfg := &config.WatcherConfig{
@@ -282,6 +370,201 @@ func TestGlogParsing(t *testing.T) {
 
 }
 
+func TestGoPanicHandling(t *testing.T) {
+	trace := []string{
+		"panic: runtime error: invalid memory address or nil pointer dereference",
+		"[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x4a1b2c]",
+		"",
+		"goroutine 7 [running]:",
+		"panic({0x4c2b40, 0x5b7e60})",
+		"\t/usr/local/go/src/runtime/panic.go:884 +0x213",
+		"main.(*Server).handle(0x0, {0xc000012345, 0x5})",
+		"\t/app/server.go:42 +0x1c",
+		"created by main.main in goroutine 1",
+		"\t/app/main.go:10 +0x25",
+		"",
+		"goroutine 1 [chan receive]:",
+		"main.main()",
+		"\t/app/main.go:12 +0x30",
+		"exit status 2",
+	}
+	var lines []string
+	lines = append(lines, `{"msg": "starting"}`)
+	lines = append(lines, trace...)
+	lines = append(lines, `{"msg": "restarted"}`, "panic: not a real panic", `{"msg": "done"}`)
+
+	tc := testCase{
+		config: `
+parser: json
+dataset: kubernetestest`,
+		lines: lines,
+		output: []event.Event{
+			{
+				Data:       map[string]interface{}{"msg": "starting"},
+				Dataset:    "kubernetestest",
+				Path:       "/tmp/testpath",
+				RawMessage: `{"msg": "starting"}`,
+			},
+			{
+				Data: map[string]interface{}{
+					"panic.message":    "runtime error: invalid memory address or nil pointer dereference",
+					"panic.goroutine":  7,
+					"panic.function":   "main.(*Server).handle",
+					"panic.file":       "/app/server.go",
+					"panic.line":       42,
+					"panic.stacktrace": strings.Join(trace, "\n"),
+				},
+				Dataset:    "kubernetestest",
+				Path:       "/tmp/testpath",
+				RawMessage: strings.Join(trace, "\n"),
+			},
+			{
+				Data:       map[string]interface{}{"msg": "restarted"},
+				Dataset:    "kubernetestest",
+				Path:       "/tmp/testpath",
+				RawMessage: `{"msg": "restarted"}`,
+			},
+			// "panic: not a real panic" isn't followed by a trace, so it's
+			// passed to the JSON parser, which fails to parse it.
+			{
+				Data:       map[string]interface{}{"msg": "done"},
+				Dataset:    "kubernetestest",
+				Path:       "/tmp/testpath",
+				RawMessage: `{"msg": "done"}`,
+			},
+		},
+	}
+	tc.check(t)
+}
+
+func TestGoPanicFlushedAfterTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { panicFlushTimeout = timeout }(panicFlushTimeout)
+	panicFlushTimeout = 10 * time.Millisecond
+
+	mt := &MockTransmitter{}
+	cfg := &config.WatcherConfig{
+		Dataset: "kubernetestest",
+		Parser:  &config.ParserConfig{Name: "nop"},
+	}
+	hf, err := NewLineHandlerFactoryFromConfig(cfg, &unwrappers.DockerJSONLogUnwrapper{}, mt)
+	assert.NoError(t, err)
+	handler := hf.New("/tmp/testpath").(*LineHandlerImpl)
+	handler.Handle(`{"log": "fatal error: concurrent map writes\n", "stream":"stderr","time":"2024-03-01T12:00:00Z"}`)
+	handler.Handle(`{"log": "\n", "stream":"stderr","time":"2024-03-01T12:00:00Z"}`)
+	handler.Handle(`{"log": "goroutine 12 [running]:\n", "stream":"stderr","time":"2024-03-01T12:00:00Z"}`)
+	handler.Handle(`{"log": "runtime.fatal({0x4d2c1e?, 0x0?})\n", "stream":"stderr","time":"2024-03-01T12:00:00Z"}`)
+	handler.Handle(`{"log": "\t/usr/local/go/src/runtime/panic.go:1061 +0x5d\n", "stream":"stderr","time":"2024-03-01T12:00:00Z"}`)
+
+	assert.Eventually(t, func() bool {
+		handler.mu.Lock()
+		defer handler.mu.Unlock()
+		return len(mt.events) == 1
+	}, time.Second, 5*time.Millisecond)
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	assert.Equal(t, map[string]interface{}{
+		"panic.message":    "concurrent map writes",
+		"panic.goroutine":  12,
+		"panic.function":   "runtime.fatal",
+		"panic.file":       "/usr/local/go/src/runtime/panic.go",
+		"panic.line":       1061,
+		"panic.stacktrace": "fatal error: concurrent map writes\n\ngoroutine 12 [running]:\nruntime.fatal({0x4d2c1e?, 0x0?})\n\t/usr/local/go/src/runtime/panic.go:1061 +0x5d",
+	}, mt.events[0].Data)
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), mt.events[0].Timestamp)
+}
+
+func TestParserFlushedAfterTimeout(t *testing.T) {
+	defer func(timeout time.Duration) { parserFlushTimeout = timeout }(parserFlushTimeout)
+	parserFlushTimeout = 10 * time.Millisecond
+
+	mt := &MockTransmitter{}
+	cfg := &config.WatcherConfig{
+		Dataset: "kubernetestest",
+		Parser:  &config.ParserConfig{Name: "java"},
+	}
+	hf, err := NewLineHandlerFactoryFromConfig(cfg, &unwrappers.RawLogUnwrapper{}, mt)
+	assert.NoError(t, err)
+	handler := hf.New("/tmp/testpath").(*LineHandlerImpl)
+	handler.Handle(`2024-03-01 12:00:00.000 [main] INFO  com.example.App - Starting`)
+	handler.Handle(`2024-03-01 12:00:01.000 [main] ERROR com.example.App - Request failed`)
+	handler.Handle(`java.lang.IllegalStateException: boom`)
+	handler.Handle(`	at com.example.App.handle(App.java:42)`)
+
+	// The first record is sent when the second starts, and the second once
+	// the file goes quiet
+	handler.mu.Lock()
+	assert.Equal(t, 1, len(mt.events))
+	handler.mu.Unlock()
+	assert.Eventually(t, func() bool {
+		handler.mu.Lock()
+		defer handler.mu.Unlock()
+		return len(mt.events) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	assert.Equal(t, "Starting", mt.events[0].Data["message"])
+	assert.Equal(t, "Request failed", mt.events[1].Data["message"])
+	assert.Equal(t, "java.lang.IllegalStateException", mt.events[1].Data["exception.type"])
+	assert.Equal(t, "java.lang.IllegalStateException: boom\n\tat com.example.App.handle(App.java:42)", mt.events[1].Data["exception.stacktrace"])
+	assert.Equal(t, "kubernetestest", mt.events[1].Dataset)
+}
+
+func TestParserFlushTimerOnlyWhenHolding(t *testing.T) {
+	mt := &MockTransmitter{}
+	cfg := &config.WatcherConfig{
+		Dataset: "kubernetestest",
+		Parser:  &config.ParserConfig{Name: "fallback", Parsers: []*config.ParserConfig{{Name: "json"}, {Name: "nop"}}},
+	}
+	hf, err := NewLineHandlerFactoryFromConfig(cfg, &unwrappers.RawLogUnwrapper{}, mt)
+	assert.NoError(t, err)
+	handler := hf.New("/tmp/testpath").(*LineHandlerImpl)
+	handler.Handle(`{"status": 200}`)
+	handler.Handle(`Starting server`)
+	assert.Nil(t, handler.flushTimer)
+
+	cfg.Parser = &config.ParserConfig{Name: "java"}
+	hf, err = NewLineHandlerFactoryFromConfig(cfg, &unwrappers.RawLogUnwrapper{}, mt)
+	assert.NoError(t, err)
+	handler = hf.New("/tmp/testpath").(*LineHandlerImpl)
+	handler.Handle(`2024-03-01 12:00:00.000 [main] INFO  com.example.App - Starting`)
+	handler.mu.Lock()
+	timer := handler.flushTimer
+	handler.mu.Unlock()
+	assert.NotNil(t, timer)
+	handler.Handle(`2024-03-01 12:00:01.000 [main] INFO  com.example.App - Started`)
+	handler.mu.Lock()
+	defer handler.mu.Unlock()
+	assert.Same(t, timer, handler.flushTimer)
+}
+
+type backgroundProcessor struct {
+	running bool
+}
+
+func (p *backgroundProcessor) Init(map[string]interface{}) error { return nil }
+func (p *backgroundProcessor) Process(*event.Event) bool         { return true }
+func (p *backgroundProcessor) Start()                            { p.running = true }
+func (p *backgroundProcessor) Stop()                             { p.running = false }
+
+func TestLineHandlerFactoryStartsAndStopsProcessors(t *testing.T) {
+	bp := &backgroundProcessor{}
+	cfg := &config.WatcherConfig{
+		Dataset: "kubernetestest",
+		Parser:  &config.ParserConfig{Name: "json"},
+	}
+	hf, err := NewLineHandlerFactoryFromConfig(cfg, &unwrappers.RawLogUnwrapper{}, &MockTransmitter{}, bp)
+	assert.NoError(t, err)
+	// Building the factory, e.g. to validate a configuration, doesn't start
+	// anything
+	assert.False(t, bp.running)
+	hf.Start()
+	assert.True(t, bp.running)
+	hf.Stop()
+	assert.False(t, bp.running)
+}
+
 func TestRedisParsing(t *testing.T) {
 	mt := &MockTransmitter{}
 	cfg := &config.WatcherConfig{
@@ -585,6 +868,35 @@ processors:
 	assert.Equal(t, mt.events[1], expected1)
 }
 
+func TestConditionalProcessor(t *testing.T) {
+	mt := &MockTransmitter{}
+
+	cfg, err := watcherConfigFromYAML(`
+dataset: kubernetestest
+parser: json
+processors:
+- drop_field:
+    field: body
+    if:
+      and:
+        - field: kind
+          equals: http
+        - field: status
+          lt: 500`)
+	assert.NoError(t, err)
+
+	hf, err := NewLineHandlerFactoryFromConfig(cfg, &unwrappers.RawLogUnwrapper{}, mt)
+	assert.NoError(t, err)
+	handler := hf.New("/tmp/testpath")
+	handler.Handle(`{"kind": "http", "status": 200, "body": "ok"}`)
+	handler.Handle(`{"kind": "http", "status": 503, "body": "unavailable"}`)
+	handler.Handle(`{"kind": "grpc", "status": 0, "body": "ok"}`)
+	assert.Equal(t, 3, len(mt.events))
+	assert.Equal(t, map[string]interface{}{"kind": "http", "status": float64(200)}, mt.events[0].Data)
+	assert.Equal(t, "unavailable", mt.events[1].Data["body"])
+	assert.Equal(t, "ok", mt.events[2].Data["body"])
+}
+
 func TestStaticSampling(t *testing.T) {
 	mt := &MockTransmitter{}
 	cfg, err := watcherConfigFromYAML(`
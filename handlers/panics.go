@@ -0,0 +1,131 @@
+package handlers
+
+// When a Go program panics, the runtime writes the panic message and the
+// stack of every goroutine to stderr, e.g.
+//
+//	panic: runtime error: invalid memory address or nil pointer dereference
+//	[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x4a1b2c]
+//
+//	goroutine 1 [running]:
+//	main.(*Server).handle(0x0)
+//		/app/server.go:42 +0x1c
+//	main.main()
+//		/app/main.go:10 +0x25
+//
+// None of the parsers can make sense of this line by line, so handlers
+// collect it into a single event before the watcher's parser sees it.
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
+)
+
+// How long to wait for more of a trace before sending it. The program has
+// usually exited, so there won't be another line to end it.
+var panicFlushTimeout = 2 * time.Second
+
+var (
+	goPanicStart     = regexp.MustCompile(`^(?:panic|fatal error): (.*)$`)
+	goPanicPreamble  = regexp.MustCompile(`^(?:\[signal .*\]|\t?\[recovered\].*|\tpanic: .*|panic: .*|)$`)
+	goroutineHeader  = regexp.MustCompile(`^goroutine (\d+) \[[^\]]*\]:$`)
+	goFunctionLine   = regexp.MustCompile(`^(?:created by \S+.*|\S.*\(.*\)|\.\.\.\d* ?additional frames elided\.\.\.)$`)
+	goFileLine       = regexp.MustCompile(`^\t(.*):(\d+)(?: \+0x[0-9a-f]+)?$`)
+	goExitStatusLine = regexp.MustCompile(`^exit status \d+$`)
+)
+
+// goPanic holds the lines of a trace, along with the events the unwrapper
+// produced for them, which are parsed as usual if the lines turn out not to be
+// a panic after all.
+type goPanic struct {
+	events       []*event.Event
+	sawGoroutine bool
+}
+
+// accepts returns whether line continues the trace.
+func (p *goPanic) accepts(line string) bool {
+	if !p.sawGoroutine {
+		if goroutineHeader.MatchString(line) {
+			p.sawGoroutine = true
+			return true
+		}
+		return goPanicPreamble.MatchString(line)
+	}
+	return line == "" ||
+		goroutineHeader.MatchString(line) ||
+		goFileLine.MatchString(line) ||
+		goFunctionLine.MatchString(line) ||
+		goExitStatusLine.MatchString(line)
+}
+
+// event builds the event sent for a complete trace.
+func (p *goPanic) event() *event.Event {
+	lines := make([]string, len(p.events))
+	for i, ev := range p.events {
+		lines[i] = ev.RawMessage
+	}
+	trace := strings.TrimRight(strings.Join(lines, "\n"), "\n")
+
+	data := map[string]interface{}{
+		"panic.stacktrace": trace,
+	}
+	if m := goPanicStart.FindStringSubmatch(lines[0]); m != nil {
+		data["panic.message"] = strings.TrimSuffix(m[1], " [recovered]")
+	}
+
+	// Report the frame that panicked: the first frame of the first goroutine
+	// that isn't in the runtime, or failing that its very first frame.
+	var function, file, lineno string
+	inFirstGoroutine := false
+	for i, line := range lines {
+		if m := goroutineHeader.FindStringSubmatch(line); m != nil {
+			if inFirstGoroutine {
+				break
+			}
+			inFirstGoroutine = true
+			if id, err := strconv.Atoi(m[1]); err == nil {
+				data["panic.goroutine"] = id
+			}
+			continue
+		}
+		if !inFirstGoroutine || i+1 >= len(lines) || !goFunctionLine.MatchString(line) {
+			continue
+		}
+		m := goFileLine.FindStringSubmatch(lines[i+1])
+		if m == nil {
+			continue
+		}
+		isRuntime := strings.HasPrefix(line, "panic(") || strings.HasPrefix(line, "runtime.")
+		if function == "" || !isRuntime {
+			function, file, lineno = goFunctionName(line), m[1], m[2]
+		}
+		if !isRuntime {
+			break
+		}
+	}
+	if function != "" {
+		data["panic.function"] = function
+		data["panic.file"] = file
+		if n, err := strconv.Atoi(lineno); err == nil {
+			data["panic.line"] = n
+		}
+	}
+
+	return &event.Event{
+		Data:       data,
+		Timestamp:  p.events[0].Timestamp,
+		RawMessage: trace,
+	}
+}
+
+// goFunctionName strips the arguments from a frame's function line, e.g.
+// main.(*Server).handle(0x0) becomes main.(*Server).handle.
+func goFunctionName(line string) string {
+	if i := strings.LastIndex(line, "("); i > 0 {
+		return line[:i]
+	}
+	return line
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
 	"github.com/honeycombio/honeycomb-kubernetes-agent/handlers"
 	"github.com/honeycombio/honeycomb-kubernetes-agent/podtailer"
+	"github.com/honeycombio/honeycomb-kubernetes-agent/processors"
 	"github.com/honeycombio/honeycomb-kubernetes-agent/tailer"
 	"github.com/honeycombio/honeycomb-kubernetes-agent/transmission"
 	"github.com/honeycombio/honeycomb-kubernetes-agent/unwrappers"
@@ -67,7 +68,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	var schema *processors.SchemaProcessor
+	if cfg.SchemaFile != "" {
+		schema, err = processors.LoadSchemaFile(cfg.SchemaFile)
+		if err != nil {
+			logrus.WithError(err).Fatal("Error reading schema file")
+		}
+	}
+
 	if flags.Validate {
+		// Build each watcher's parser and processors too, so that invalid
+		// options are caught here rather than when the first pod appears.
+		if err := validateWatchers(cfg.Watchers); err != nil {
+			logrus.WithError(err).Fatal("Error in watcher configuration")
+		}
 		logrus.Println("Configuration looks good!")
 		os.Exit(0)
 	}
@@ -129,7 +143,7 @@ func main() {
 			logrus.WithError(err).Fatal("Error in watcher configuration")
 		} else {
 
-			pws, pts := createLogTailers(cfg)
+			pws, pts := createLogTailers(cfg, schema)
 			for _, pw := range pws {
 				pw.Start()
 				defer pw.Stop()
@@ -146,7 +160,7 @@ func main() {
 	waitForSignal()
 }
 
-func createLogTailers(config *config.Config) ([]*tailer.PathWatcher, []*podtailer.PodSetTailer) {
+func createLogTailers(config *config.Config, schema *processors.SchemaProcessor) ([]*tailer.PathWatcher, []*podtailer.PodSetTailer) {
 	transmitter := &transmission.HoneycombTransmitter{}
 
 	kubeClient, err := newKubeClient()
@@ -165,6 +179,11 @@ func createLogTailers(config *config.Config) ([]*tailer.PathWatcher, []*podtaile
 		logrus.WithError(err).Error("Error initializing state recorder. Agent progress won't be persisted across restarts.")
 	}
 
+	var extraProcessors []processors.Processor
+	if schema != nil {
+		extraProcessors = append(extraProcessors, schema)
+	}
+
 	pws := make([]*tailer.PathWatcher, 0)
 	pts := make([]*podtailer.PodSetTailer, 0)
 
@@ -176,7 +195,8 @@ func createLogTailers(config *config.Config) ([]*tailer.PathWatcher, []*podtaile
 			handlerFactory, err := handlers.NewLineHandlerFactoryFromConfig(
 				watcherConfig,
 				&unwrappers.RawLogUnwrapper{},
-				transmitter)
+				transmitter,
+				extraProcessors...)
 			if err != nil {
 				// This shouldn't happen, since we check for configuration errors
 				// before actually setting up the watcher
@@ -199,6 +219,7 @@ func createLogTailers(config *config.Config) ([]*tailer.PathWatcher, []*podtaile
 				kubeClient,
 				config.LegacyLogPaths,
 				config.AdditionalFields,
+				schema,
 			)
 			pts = append(pts, pt)
 		}
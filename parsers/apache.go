@@ -0,0 +1,220 @@
+package parsers
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Apache's predefined log formats
+// https://httpd.apache.org/docs/current/mod/mod_log_config.html#examples
+const apacheCommonLogFormat = `%h %l %u %t "%r" %>s %b`
+const apacheCombinedLogFormat = `%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-agent}i"`
+
+// Matches a mod_log_config directive, e.g. %h, %>s, %{User-agent}i or
+// %!200,304{Referer}i. Status conditions and the </> modifiers don't affect
+// how the line is parsed, so they're dropped.
+var apacheDirective = regexp.MustCompile(`%[<>]?(?:!?[0-9]+(?:,[0-9]+)*)?(?:\{([^}]*)\})?([a-zA-Z%])`)
+
+// Field names for directives that don't take a name argument. These follow the
+// names of the equivalent nginx variables where there is one.
+var apacheFields = map[string]string{
+	"a": "remote_addr",
+	"A": "server_addr",
+	"B": "body_bytes_sent",
+	"b": "body_bytes_sent",
+	"D": "request_time_us",
+	"f": "request_filename",
+	"h": "remote_host",
+	"H": "server_protocol",
+	"I": "bytes_received",
+	"k": "keepalive_requests",
+	"l": "remote_logname",
+	"L": "log_id",
+	"m": "request_method",
+	"O": "bytes_sent",
+	"p": "server_port",
+	"P": "pid",
+	"q": "query_string",
+	"r": "request",
+	"R": "handler",
+	"s": "status",
+	"S": "bytes_transferred",
+	"T": "request_time",
+	"u": "remote_user",
+	"U": "uri",
+	"v": "virtual_host",
+	"V": "server_name",
+	"X": "connection_status",
+}
+
+// Prefixes for directives that name a header, cookie, etc.
+var apacheNamedFieldPrefixes = map[string]string{
+	"i": "http_",
+	"o": "sent_http_",
+	"C": "cookie_",
+	"e": "env_",
+	"n": "note_",
+}
+
+var nonWordChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+type ApacheParserFactory struct {
+	re *extRegexp
+}
+
+func (pf *ApacheParserFactory) Init(options map[string]interface{}) error {
+	logFormat := apacheCombinedLogFormat
+	if logFormatOption, ok := options["log_format"]; ok {
+		typedLogFormatOption, ok := logFormatOption.(string)
+		if !ok {
+			return fmt.Errorf("Unexpected type for log_format option (expected string, got %v)", reflect.TypeOf(logFormatOption))
+		}
+
+		switch typedLogFormatOption {
+		case "common":
+			logFormat = apacheCommonLogFormat
+		case "combined":
+			logFormat = apacheCombinedLogFormat
+		default:
+			logFormat = typedLogFormatOption
+		}
+	}
+
+	expr, err := apacheFormatToRegexp(logFormat)
+	if err != nil {
+		return err
+	}
+	re, err := newExtRegexp(expr)
+	if err != nil {
+		return fmt.Errorf("Invalid Apache log format `%s`: %v", logFormat, err)
+	}
+	pf.re = re
+	return nil
+}
+
+func (pf *ApacheParserFactory) New() Parser {
+	return &ApacheParser{re: pf.re}
+}
+
+type ApacheParser struct {
+	re *extRegexp
+}
+
+func (p *ApacheParser) Parse(line string) (map[string]interface{}, error) {
+	_, captures := p.re.FindStringSubmatchMap(line)
+	if captures == nil {
+		return nil, fmt.Errorf("Couldn't parse line with Apache log format: %s", line)
+	}
+	return typeifyParsedLine(captures), nil
+}
+
+// apacheFormatToRegexp translates a mod_log_config format string into a
+// regular expression with a named group for each directive.
+func apacheFormatToRegexp(logFormat string) (string, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	locs := apacheDirective.FindAllStringSubmatchIndex(logFormat, -1)
+	fields := make(map[string]bool, len(locs))
+	prev := 0
+	for i, loc := range locs {
+		expr.WriteString(regexp.QuoteMeta(logFormat[prev:loc[0]]))
+		prev = loc[1]
+
+		directive := logFormat[loc[0]:loc[1]]
+		var arg string
+		if loc[2] >= 0 {
+			arg = logFormat[loc[2]:loc[3]]
+		}
+		name := logFormat[loc[4]:loc[5]]
+
+		// Each value runs up to the next literal character in the format.
+		// Values that are directly followed by another directive can't be
+		// delimited that way, so they match as little as possible instead.
+		valuePattern := ".*"
+		if loc[1] < len(logFormat) {
+			if i+1 < len(locs) && locs[i+1][0] == loc[1] {
+				valuePattern = ".*?"
+			} else {
+				valuePattern = "[^" + regexp.QuoteMeta(logFormat[loc[1]:loc[1]+1]) + "]*"
+			}
+		}
+
+		var field string
+		switch {
+		case name == "%":
+			expr.WriteString("%")
+			continue
+		case name == "t" && arg == "":
+			// The default time format is wrapped in brackets
+			expr.WriteString(`\[(?P<time_local>[^\]]*)\]`)
+			continue
+		case name == "t":
+			valuePattern = apacheTimePattern(arg)
+			field = "time"
+			// Formats like sec and msec can be logged alongside a
+			// formatted time
+			if unit := apacheEpochTimeUnit(arg); unit != "" {
+				field = "time_" + unit
+			}
+		case name == "T" && arg == "ms":
+			field = "request_time_ms"
+		case name == "T" && arg == "us":
+			field = "request_time_us"
+		case name == "q":
+			// The query string includes its leading `?`, if there is one
+			valuePattern = `(?:\?[^\s"]*)?`
+			field = "query_string"
+		case name == "U":
+			valuePattern = `[^\s"?]*`
+			field = "uri"
+		default:
+			if prefix, ok := apacheNamedFieldPrefixes[name]; ok {
+				if arg == "" {
+					return "", fmt.Errorf("Apache log format directive %s requires a name", directive)
+				}
+				field = prefix + nonWordChars.ReplaceAllString(strings.ToLower(arg), "_")
+			} else if f, ok := apacheFields[name]; ok {
+				field = f
+			} else {
+				return "", fmt.Errorf("Unsupported Apache log format directive %s", directive)
+			}
+		}
+		// %b and %B both give body_bytes_sent, for example, and a later
+		// value would silently replace an earlier one
+		if fields[field] {
+			return "", fmt.Errorf("Apache log format directive %s repeats the %s field", directive, field)
+		}
+		fields[field] = true
+		expr.WriteString("(?P<" + field + ">" + valuePattern + ")")
+	}
+	expr.WriteString(regexp.QuoteMeta(logFormat[prev:]))
+	expr.WriteString("$")
+	return expr.String(), nil
+}
+
+// apacheTimePattern matches a time written with %{format}t. Times given as a
+// number since the epoch are digits, and strftime formats can contain spaces,
+// so the value spans as many words as the format does.
+func apacheTimePattern(format string) string {
+	if apacheEpochTimeUnit(format) != "" {
+		return `\d+`
+	}
+	words := len(strings.Fields(format))
+	if words <= 1 {
+		return `\S+`
+	}
+	return fmt.Sprintf(`\S+(?:\s+\S+){%d}`, words-1)
+}
+
+// apacheEpochTimeUnit returns the unit of a %{format}t time that's logged as a
+// number, or "" for strftime formats.
+func apacheEpochTimeUnit(format string) string {
+	format = strings.TrimPrefix(strings.TrimPrefix(format, "begin:"), "end:")
+	switch format {
+	case "sec", "msec", "usec", "msec_frac", "usec_frac":
+		return format
+	}
+	return ""
+}
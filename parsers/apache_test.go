@@ -0,0 +1,89 @@
+package parsers
+
+import (
+	"testing"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApacheParser(t *testing.T) {
+	tc := []struct {
+		logFormat string
+		line      string
+		expected  map[string]interface{}
+	}{
+		{
+			logFormat: "",
+			line:      `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08 [en] (Win98; I ;Nav)"`,
+			expected: map[string]interface{}{
+				"remote_host":     "127.0.0.1",
+				"remote_user":     "frank",
+				"time_local":      "10/Oct/2000:13:55:36 -0700",
+				"request":         "GET /apache_pb.gif HTTP/1.0",
+				"status":          int64(200),
+				"body_bytes_sent": int64(2326),
+				"http_referer":    "http://www.example.com/start.html",
+				"http_user_agent": "Mozilla/4.08 [en] (Win98; I ;Nav)",
+			},
+		},
+		{
+			logFormat: "common",
+			line:      `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "POST /login HTTP/1.1" 302 -`,
+			expected: map[string]interface{}{
+				"remote_host": "10.0.0.1",
+				"time_local":  "10/Oct/2000:13:55:36 -0700",
+				"request":     "POST /login HTTP/1.1",
+				"status":      int64(302),
+			},
+		},
+		{
+			logFormat: `%a %{X-Request-ID}i %m %U%q %>s %D %{ms}T %400,501{User-agent}i %%`,
+			line:      `10.0.0.2 abc-123 GET /search?q=x 200 1534 1 curl/7.64 %`,
+			expected: map[string]interface{}{
+				"remote_addr":       "10.0.0.2",
+				"http_x_request_id": "abc-123",
+				"request_method":    "GET",
+				"uri":               "/search",
+				"query_string":      "?q=x",
+				"status":            int64(200),
+				"request_time_us":   int64(1534),
+				"request_time_ms":   int64(1),
+				"http_user_agent":   "curl/7.64",
+			},
+		},
+		{
+			logFormat: `%h %{%d/%b/%Y %T}t %{msec}t "%r" %>s`,
+			line:      `10.0.0.3 10/Oct/2000 13:55:36 971211336123 "GET / HTTP/1.1" 200`,
+			expected: map[string]interface{}{
+				"remote_host": "10.0.0.3",
+				"time":        "10/Oct/2000 13:55:36",
+				"time_msec":   int64(971211336123),
+				"request":     "GET / HTTP/1.1",
+				"status":      int64(200),
+			},
+		},
+	}
+
+	for _, tt := range tc {
+		cfg := &config.ParserConfig{Name: "apache"}
+		if tt.logFormat != "" {
+			cfg.Options = map[string]interface{}{"log_format": tt.logFormat}
+		}
+		pf, err := NewParserFactory(cfg)
+		assert.NoError(t, err)
+		parsed, err := pf.New().Parse(tt.line)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, parsed)
+	}
+}
+
+func TestApacheParserInvalidFormat(t *testing.T) {
+	for _, logFormat := range []string{`%h %J`, `%h %i`, `%h %b %B`, `%h %h`} {
+		_, err := NewParserFactory(&config.ParserConfig{
+			Name:    "apache",
+			Options: map[string]interface{}{"log_format": logFormat},
+		})
+		assert.Error(t, err, logFormat)
+	}
+}
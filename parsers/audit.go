@@ -29,6 +29,8 @@ type AuditParser struct {
 	cache        *lru.Cache
 }
 
+func (p *AuditParser) Stateful() bool { return true }
+
 func (p *AuditParser) Parse(line string) (map[string]interface{}, error) {
 	data, err := p.keyvalParser.Parse(line)
 	if err != nil {
@@ -0,0 +1,158 @@
+package parsers
+
+// The auto parser looks at each line to decide which parser to use, for
+// watchers that cover applications with different log formats. Unlike the
+// fallback parser, it only tries a parser when the line looks like that
+// parser's format, so plain text isn't mistaken for e.g. logfmt.
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+)
+
+var (
+	autoGlogLine   = regexp.MustCompile(`^[IWEF]\d{4} \d{2}:\d{2}:\d{2}\.\d+\s+\d+ `)
+	autoCLFLine    = regexp.MustCompile(`^\S+ \S+ \S+ \[[^\]]+\] "`)
+	autoKeyvalLine = regexp.MustCompile(`^[\w.\-]+=(?:"[^"]*"|\S*)\s+[\w.\-]+=`)
+)
+
+// The formats the auto parser recognizes, in the order they're tried, and the
+// parsers it uses for them.
+var autoFormats = []struct {
+	format  string
+	matches func(line string) bool
+	config  *config.ParserConfig
+}{
+	{
+		format:  "json",
+		matches: func(line string) bool { return len(line) > 0 && line[0] == '{' },
+		config:  &config.ParserConfig{Name: "json"},
+	},
+	{
+		format:  "klog",
+		matches: autoGlogLine.MatchString,
+		config:  &config.ParserConfig{Name: "klog"},
+	},
+	{
+		format:  "clf",
+		matches: autoCLFLine.MatchString,
+		config:  &config.ParserConfig{Name: "apache", Options: map[string]interface{}{"log_format": "combined"}},
+	},
+	{
+		format:  "clf",
+		matches: autoCLFLine.MatchString,
+		config:  &config.ParserConfig{Name: "apache", Options: map[string]interface{}{"log_format": "common"}},
+	},
+	{
+		format:  "keyval",
+		matches: autoKeyvalLine.MatchString,
+		config:  &config.ParserConfig{Name: "keyval"},
+	},
+	{
+		format:  "text",
+		matches: func(string) bool { return true },
+		config:  &config.ParserConfig{Name: "nop"},
+	},
+}
+
+type AutoParserFactory struct {
+	factories []ParserFactory
+	field     string
+}
+
+func (pf *AutoParserFactory) Init(options map[string]interface{}) error {
+	pf.field = defaultFallbackField
+	if fieldOption, ok := options["field"]; ok {
+		typedFieldOption, ok := fieldOption.(string)
+		if !ok {
+			return fmt.Errorf("Unexpected type for field option (expected string, got %v)", reflect.TypeOf(fieldOption))
+		}
+		pf.field = typedFieldOption
+	}
+
+	pf.factories = make([]ParserFactory, len(autoFormats))
+	for i, f := range autoFormats {
+		factory, err := NewParserFactory(f.config)
+		if err != nil {
+			return fmt.Errorf("Error setting up %s parser: %v", f.format, err)
+		}
+		pf.factories[i] = factory
+	}
+	return nil
+}
+
+func (pf *AutoParserFactory) New() Parser {
+	parsers := make([]Parser, len(pf.factories))
+	for i, factory := range pf.factories {
+		parsers[i] = factory.New()
+	}
+	return &AutoParser{parsers: parsers, field: pf.field, pending: -1}
+}
+
+type AutoParser struct {
+	parsers []Parser
+	field   string
+	// The parser that's in the middle of a multi-line record, or -1
+	pending int
+}
+
+func (p *AutoParser) Stateful() bool { return true }
+
+func (p *AutoParser) Holding() bool {
+	for _, parser := range p.parsers {
+		if fp, ok := parser.(FlushableParser); ok && fp.Holding() {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *AutoParser) Flush() map[string]interface{} {
+	for i, parser := range p.parsers {
+		if fp, ok := parser.(FlushableParser); ok {
+			if data := fp.Flush(); data != nil {
+				return p.tag(i, data)
+			}
+		}
+	}
+	return nil
+}
+
+func (p *AutoParser) Parse(line string) (map[string]interface{}, error) {
+	if p.pending != -1 {
+		i := p.pending
+		p.pending = -1
+		if data, err := p.parsers[i].Parse(line); err == nil {
+			return p.tag(i, data), nil
+		}
+	}
+
+	for i, f := range autoFormats {
+		if !f.matches(line) {
+			continue
+		}
+		data, err := p.parsers[i].Parse(line)
+		if err != nil {
+			continue
+		}
+		return p.tag(i, data), nil
+	}
+	// Not reached, since the nop parser accepts every line
+	return nil, fmt.Errorf("Couldn't detect format of line: %s", line)
+}
+
+func (p *AutoParser) tag(i int, data map[string]interface{}) map[string]interface{} {
+	if data == nil {
+		// The parser consumed the line without producing an event yet, so
+		// the next line should go to it first.
+		p.pending = i
+		return nil
+	}
+	if p.field != "" {
+		data[p.field] = autoFormats[i].format
+	}
+	return data
+}
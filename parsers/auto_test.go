@@ -0,0 +1,80 @@
+package parsers
+
+import (
+	"testing"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAutoParser(t *testing.T) {
+	pf, err := NewParserFactory(&config.ParserConfig{Name: "auto"})
+	assert.NoError(t, err)
+	parser := pf.New()
+
+	tc := []struct {
+		line   string
+		format string
+		field  string
+		value  interface{}
+	}{
+		{`{"level": "info", "msg": "hello"}`, "json", "msg", "hello"},
+		{`{"level": "info", "msg": `, "text", "log", `{"level": "info", "msg": `},
+		{`I0301 12:00:00.123456       1 main.go:42] "Starting" version="1.2.3"`, "klog", "version", "1.2.3"},
+		{`W0301 12:00:00.123456       1 main.go:42] plain glog output`, "klog", "message", "plain glog output"},
+		{`10.0.0.1 - - [01/Mar/2024:12:00:00 +0000] "GET / HTTP/1.1" 200 612 "-" "curl/8.0"`, "clf", "http_user_agent", "curl/8.0"},
+		{`10.0.0.1 - frank [01/Mar/2024:12:00:00 +0000] "GET / HTTP/1.1" 200 612`, "clf", "remote_user", "frank"},
+		{`time=2024-03-01T12:00:00Z level=info msg="request done" status=200`, "keyval", "msg", "request done"},
+		{`Starting server on port=8080`, "text", "log", `Starting server on port=8080`},
+		{`==> banner <==`, "text", "log", `==> banner <==`},
+	}
+	for _, tt := range tc {
+		parsed, err := parser.Parse(tt.line)
+		assert.NoError(t, err, tt.line)
+		assert.Equal(t, tt.format, parsed[defaultFallbackField], tt.line)
+		assert.Equal(t, tt.value, parsed[tt.field], tt.line)
+	}
+}
+
+func TestAutoParserMultiLine(t *testing.T) {
+	pf, err := NewParserFactory(&config.ParserConfig{Name: "auto", Options: map[string]interface{}{"field": "format"}})
+	assert.NoError(t, err)
+	parser := pf.New()
+
+	parsed, err := parser.Parse(`I0301 12:00:00.123456       1 main.go:42] "Config" data=<`)
+	assert.NoError(t, err)
+	assert.Nil(t, parsed)
+	parsed, err = parser.Parse("\tkey=value")
+	assert.NoError(t, err)
+	assert.Nil(t, parsed)
+	parsed, err = parser.Parse(" >")
+	assert.NoError(t, err)
+	assert.Equal(t, "klog", parsed["format"])
+	assert.Equal(t, "key=value", parsed["data"])
+}
+
+func TestAutoParserInvalidOptions(t *testing.T) {
+	_, err := NewParserFactory(&config.ParserConfig{Name: "auto", Options: map[string]interface{}{"field": 1}})
+	assert.Error(t, err)
+}
+
+func TestAutoParserKlogUnterminatedValue(t *testing.T) {
+	pf, err := NewParserFactory(&config.ParserConfig{Name: "auto"})
+	assert.NoError(t, err)
+	parser := pf.New().(*AutoParser)
+
+	parsed, err := parser.Parse(`I1025 00:15:15.525108       1 example.go:79] "Config loaded" config=<`)
+	assert.NoError(t, err)
+	assert.Nil(t, parsed)
+	// Plain text ends the value, and is still sent as text
+	parsed, err = parser.Parse("Starting server")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"log": "Starting server", "meta.parser": "text"}, parsed)
+
+	assert.True(t, parser.Holding())
+	parsed = parser.Flush()
+	assert.Equal(t, "Config loaded", parsed["message"])
+	assert.Equal(t, "", parsed["config"])
+	assert.Equal(t, "klog", parsed["meta.parser"])
+	assert.Nil(t, parser.Flush())
+}
@@ -0,0 +1,189 @@
+package parsers
+
+// Parses ArcSight Common Event Format (CEF) records, e.g.
+// CEF:0|Security|threatmanager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232
+// optionally preceded by a syslog header.
+// https://www.microfocus.com/documentation/arcsight/arcsight-smartconnectors/pdfdoc/common-event-format-v25/common-event-format-v25.pdf
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var cefHeaderFields = []string{"cef_version", "device_vendor", "device_product", "device_version", "signature_id", "name", "severity"}
+
+// Extension keys whose values are integers
+var cefIntFields = map[string]bool{
+	"cnt": true, "in": true, "out": true, "spt": true, "dpt": true,
+	"spid": true, "dpid": true, "fsize": true, "oldFileSize": true,
+	"cn1": true, "cn2": true, "cn3": true,
+	"sourceTranslatedPort": true, "destinationTranslatedPort": true,
+	"deviceDirection": true, "type": true,
+}
+
+// Extension keys whose values are timestamps
+var cefTimeFields = map[string]bool{
+	"rt": true, "start": true, "end": true, "deviceCustomDate1": true, "deviceCustomDate2": true,
+	"fileCreateTime": true, "fileModificationTime": true,
+	"oldFileCreateTime": true, "oldFileModificationTime": true,
+}
+
+var cefKey = regexp.MustCompile(`^[A-Za-z0-9_.\[\]-]+$`)
+
+type CEFParserFactory struct{}
+
+func (pf *CEFParserFactory) Init(options map[string]interface{}) error { return nil }
+
+func (pf *CEFParserFactory) New() Parser {
+	return &CEFParser{}
+}
+
+type CEFParser struct{}
+
+func (p *CEFParser) Parse(line string) (map[string]interface{}, error) {
+	start := strings.Index(line, "CEF:")
+	if start == -1 {
+		return nil, fmt.Errorf("Couldn't parse line as CEF record: %s", line)
+	}
+	header, extension, ok := splitSecurityHeader(line[start+len("CEF:"):], len(cefHeaderFields))
+	if !ok {
+		return nil, fmt.Errorf("Couldn't parse CEF header: %s", line)
+	}
+
+	ret := make(map[string]interface{})
+	if prefix := strings.TrimSpace(line[:start]); prefix != "" {
+		ret["syslog_header"] = prefix
+	}
+	for i, name := range cefHeaderFields {
+		ret[name] = header[i]
+	}
+	if severity, err := strconv.Atoi(header[6]); err == nil {
+		ret["severity"] = severity
+	}
+
+	fields := parseCEFExtension(extension)
+	for k, v := range fields {
+		// Custom fields such as cs1 are named by e.g. cs1Label, but typed by
+		// their original key
+		if label, ok := fields[k+"Label"]; ok && label != "" {
+			ret[label] = typeCEFValue(k, v)
+			continue
+		}
+		if strings.HasSuffix(k, "Label") {
+			if _, ok := fields[strings.TrimSuffix(k, "Label")]; ok {
+				continue
+			}
+		}
+		ret[k] = typeCEFValue(k, v)
+	}
+	return ret, nil
+}
+
+// splitSecurityHeader splits the first n pipe-delimited fields off s, in which
+// pipes and backslashes can be escaped with a backslash, and returns the
+// fields and the rest of s.
+func splitSecurityHeader(s string, n int) ([]string, string, bool) {
+	fields := make([]string, 0, n)
+	var field strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && (s[i+1] == '|' || s[i+1] == '\\'):
+			field.WriteByte(s[i+1])
+			i++
+		case c == '|':
+			fields = append(fields, field.String())
+			field.Reset()
+			if len(fields) == n {
+				return fields, s[i+1:], true
+			}
+		default:
+			field.WriteByte(c)
+		}
+	}
+	return nil, "", false
+}
+
+// parseCEFExtension splits the extension into its key=value pairs. Values can
+// contain spaces, so a value runs up to the space before the next key. Equals
+// signs in values are escaped with a backslash.
+func parseCEFExtension(extension string) map[string]string {
+	ret := make(map[string]string)
+	key := ""
+	valueStart := 0
+	for i := 0; i < len(extension); i++ {
+		switch extension[i] {
+		case '\\':
+			i++
+		case '=':
+			keyStart := strings.LastIndexByte(extension[valueStart:i], ' ') + 1 + valueStart
+			if key != "" && keyStart == valueStart {
+				// Not preceded by a space, so it's part of the value
+				continue
+			}
+			nextKey := extension[keyStart:i]
+			if !cefKey.MatchString(nextKey) {
+				continue
+			}
+			if key != "" {
+				ret[key] = unescapeCEFValue(strings.TrimSpace(extension[valueStart:keyStart]))
+			}
+			key = nextKey
+			valueStart = i + 1
+		}
+	}
+	if key != "" {
+		ret[key] = unescapeCEFValue(strings.TrimSpace(extension[valueStart:]))
+	}
+	return ret
+}
+
+func unescapeCEFValue(v string) string {
+	if !strings.Contains(v, `\`) {
+		return v
+	}
+	var b strings.Builder
+	for i := 0; i < len(v); i++ {
+		if v[i] != '\\' || i+1 == len(v) {
+			b.WriteByte(v[i])
+			continue
+		}
+		i++
+		switch v[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(v[i])
+		}
+	}
+	return b.String()
+}
+
+func typeCEFValue(key, value string) interface{} {
+	switch {
+	case cefIntFields[key]:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case strings.HasPrefix(key, "cfp"):
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case cefTimeFields[key]:
+		// Either milliseconds since the epoch, or e.g. `Mar 01 2024 12:00:00`
+		if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return time.UnixMilli(ms).UTC()
+		}
+		for _, layout := range []string{"Jan 02 2006 15:04:05.000 MST", "Jan 02 2006 15:04:05 MST", "Jan 02 2006 15:04:05.000", "Jan 02 2006 15:04:05"} {
+			if ts, err := time.Parse(layout, value); err == nil {
+				return ts
+			}
+		}
+	}
+	return value
+}
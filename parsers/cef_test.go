@@ -0,0 +1,56 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCEFParser(t *testing.T) {
+	pf, err := NewParserFactory(&config.ParserConfig{Name: "cef"})
+	assert.NoError(t, err)
+	parser := pf.New()
+
+	parsed, err := parser.Parse(`Mar  1 12:00:00 fw01 CEF:0|Security|threat\|manager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232 msg=Detected a threat. No action needed\=true path=C:\\Windows\nend cs1Label=Policy Name cs1=Block all act=blocked rt=1709294400000 cfp1=0.5`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"syslog_header":  "Mar  1 12:00:00 fw01",
+		"cef_version":    "0",
+		"device_vendor":  "Security",
+		"device_product": "threat|manager",
+		"device_version": "1.0",
+		"signature_id":   "100",
+		"name":           "worm successfully stopped",
+		"severity":       10,
+		"src":            "10.0.0.1",
+		"dst":            "2.1.2.2",
+		"spt":            int64(1232),
+		"msg":            "Detected a threat. No action needed=true",
+		"path":           "C:\\Windows\nend",
+		"Policy Name":    "Block all",
+		"act":            "blocked",
+		"rt":             time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		"cfp1":           0.5,
+	}, parsed)
+
+	parsed, err = parser.Parse(`CEF:0|Vendor|Product|2|login|Login failed|High|start=Mar 01 2024 12:00:00 suser=bob`)
+	assert.NoError(t, err)
+	assert.Equal(t, "High", parsed["severity"])
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC), parsed["start"])
+	assert.Equal(t, "bob", parsed["suser"])
+
+	parsed, err = parser.Parse(`CEF:0|Vendor|Product|2|scan|Port scan|5|cn1Label=Count cn1=7 cfp1Label=Score cfp1=0.75`)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), parsed["Count"])
+	assert.Equal(t, 0.75, parsed["Score"])
+
+	for _, line := range []string{
+		`not a CEF record`,
+		`CEF:0|Vendor|Product|2|login`,
+	} {
+		_, err = parser.Parse(line)
+		assert.Error(t, err, line)
+	}
+}
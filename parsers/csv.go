@@ -0,0 +1,194 @@
+package parsers
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+type CSVParserFactory struct {
+	delimiter rune
+	quote     rune
+	columns   []string
+	header    bool
+	types     map[string]string
+}
+
+func (pf *CSVParserFactory) Init(options map[string]interface{}) error {
+	pf.delimiter = ','
+	pf.quote = '"'
+
+	if delimiterOption, ok := options["delimiter"]; ok {
+		delimiter, ok := delimiterOption.(string)
+		if !ok {
+			return fmt.Errorf("Unexpected type for delimiter option (expected string, got %v)", reflect.TypeOf(delimiterOption))
+		}
+		if delimiter == "tab" || delimiter == `\t` {
+			delimiter = "\t"
+		}
+		if utf8.RuneCountInString(delimiter) != 1 {
+			return fmt.Errorf("delimiter option must be a single character, got `%s`", delimiter)
+		}
+		pf.delimiter, _ = utf8.DecodeRuneInString(delimiter)
+	}
+
+	if quoteOption, ok := options["quote"]; ok {
+		quote, ok := quoteOption.(string)
+		if !ok {
+			return fmt.Errorf("Unexpected type for quote option (expected string, got %v)", reflect.TypeOf(quoteOption))
+		}
+		switch utf8.RuneCountInString(quote) {
+		case 0:
+			// Quoting disabled
+			pf.quote = 0
+		case 1:
+			pf.quote, _ = utf8.DecodeRuneInString(quote)
+		default:
+			return fmt.Errorf("quote option must be a single character, got `%s`", quote)
+		}
+		if pf.quote == pf.delimiter {
+			return fmt.Errorf("quote and delimiter options must be different")
+		}
+	}
+
+	if columnsOption, ok := options["columns"]; ok {
+		columns, ok := columnsOption.([]interface{})
+		if !ok {
+			return fmt.Errorf("Unexpected type for columns option (expected list, got %v)", reflect.TypeOf(columnsOption))
+		}
+		pf.columns = make([]string, len(columns))
+		for i, c := range columns {
+			column, ok := c.(string)
+			if !ok {
+				return fmt.Errorf("expected column %v to be string", c)
+			}
+			pf.columns[i] = column
+		}
+	}
+
+	if headerOption, ok := options["header"]; ok {
+		header, ok := headerOption.(bool)
+		if !ok {
+			return fmt.Errorf("Unexpected type for header option (expected bool, got %v)", reflect.TypeOf(headerOption))
+		}
+		pf.header = header
+	}
+	// The agent resumes files where it left off after a restart, so the
+	// first line it reads isn't necessarily the header
+	if pf.header && len(pf.columns) == 0 {
+		return fmt.Errorf("csv header option requires the columns option to be set")
+	}
+
+	types, err := stringMapOption(options, "types")
+	if err != nil {
+		return err
+	}
+	for column, columnType := range types {
+		switch columnType {
+		case "string", "int", "float", "bool":
+		default:
+			return fmt.Errorf("Unknown type %s for column %s (expected int, float, bool or string)", columnType, column)
+		}
+	}
+	pf.types = types
+	return nil
+}
+
+func (pf *CSVParserFactory) New() Parser {
+	return &CSVParser{
+		delimiter: pf.delimiter,
+		quote:     pf.quote,
+		columns:   pf.columns,
+		header:    pf.header,
+		types:     pf.types,
+	}
+}
+
+type CSVParser struct {
+	delimiter rune
+	quote     rune
+	columns   []string
+	// Skip header rows, which are recognized by matching the columns
+	header bool
+	types  map[string]string
+}
+
+func (p *CSVParser) Parse(line string) (map[string]interface{}, error) {
+	if line == "" {
+		return nil, fmt.Errorf("Empty CSV line")
+	}
+	values, err := p.split(line)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.header && p.isHeader(values) {
+		return nil, nil
+	}
+
+	ret := make(map[string]interface{}, len(values))
+	for i, value := range values {
+		var column string
+		if i < len(p.columns) && p.columns[i] != "" {
+			column = p.columns[i]
+		} else {
+			column = fmt.Sprintf("column_%d", i+1)
+		}
+		ret[column] = convertValue(value, p.types[column])
+	}
+	return ret, nil
+}
+
+// split breaks a line into fields. A field may be wrapped in quote
+// characters, in which case it can contain the delimiter, and a doubled quote
+// character stands for a literal one.
+func (p *CSVParser) split(line string) ([]string, error) {
+	var values []string
+	var field strings.Builder
+	quoted := false
+	atFieldStart := true
+	for i := 0; i < len(line); {
+		r, size := utf8.DecodeRuneInString(line[i:])
+		i += size
+		switch {
+		case quoted && r == p.quote:
+			next, nextSize := utf8.DecodeRuneInString(line[i:])
+			if i < len(line) && next == p.quote {
+				field.WriteRune(p.quote)
+				i += nextSize
+			} else {
+				quoted = false
+			}
+		case quoted:
+			field.WriteRune(r)
+		case atFieldStart && p.quote != 0 && r == p.quote:
+			quoted = true
+			atFieldStart = false
+		case r == p.delimiter:
+			values = append(values, field.String())
+			field.Reset()
+			atFieldStart = true
+		default:
+			field.WriteRune(r)
+			atFieldStart = false
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("Unterminated quoted field in line: %s", line)
+	}
+	values = append(values, field.String())
+	return values, nil
+}
+
+func (p *CSVParser) isHeader(values []string) bool {
+	if len(values) != len(p.columns) {
+		return false
+	}
+	for i, value := range values {
+		if value != p.columns[i] {
+			return false
+		}
+	}
+	return true
+}
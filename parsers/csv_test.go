@@ -0,0 +1,88 @@
+package parsers
+
+import (
+	"testing"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVParser(t *testing.T) {
+	pf, err := NewParserFactory(&config.ParserConfig{
+		Name: "csv",
+		Options: map[string]interface{}{
+			"columns": []interface{}{"job", "status", "duration", "retried"},
+			"types": map[interface{}]interface{}{
+				"duration": "float",
+				"retried":  "bool",
+			},
+		},
+	})
+	assert.NoError(t, err)
+	parser := pf.New()
+
+	parsed, err := parser.Parse(`backup,"ok, eventually",12.5,true,extra`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"job":      "backup",
+		"status":   "ok, eventually",
+		"duration": 12.5,
+		"retried":  true,
+		"column_5": "extra",
+	}, parsed)
+
+	parsed, err = parser.Parse(`"say ""hi""",,n/a`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"job":      `say "hi"`,
+		"status":   "",
+		"duration": "n/a",
+	}, parsed)
+
+	_, err = parser.Parse(`backup,"unterminated`)
+	assert.Error(t, err)
+}
+
+func TestTSVParserWithHeader(t *testing.T) {
+	pf, err := NewParserFactory(&config.ParserConfig{
+		Name: "csv",
+		Options: map[string]interface{}{
+			"delimiter": "tab",
+			"quote":     "'",
+			"columns":   []interface{}{"table", "rows"},
+			"header":    true,
+			"types":     map[string]interface{}{"rows": "int"},
+		},
+	})
+	assert.NoError(t, err)
+	parser := pf.New()
+
+	parsed, err := parser.Parse("table\trows")
+	assert.NoError(t, err)
+	assert.Nil(t, parsed)
+
+	parsed, err = parser.Parse("'user\tlist'\t42")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"table": "user\tlist", "rows": int64(42)}, parsed)
+
+	// After a restart the agent resumes partway through a file, so a data row
+	// can come first, and mustn't be taken for the header
+	parser = pf.New()
+	parsed, err = parser.Parse("orders\t7")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"table": "orders", "rows": int64(7)}, parsed)
+}
+
+func TestCSVParserInvalidConfig(t *testing.T) {
+	testcases := []map[string]interface{}{
+		{"delimiter": "::"},
+		{"quote": ","},
+		{"columns": "a,b"},
+		{"header": true},
+		{"types": map[string]interface{}{"a": "date"}},
+	}
+	for _, options := range testcases {
+		_, err := NewParserFactory(&config.ParserConfig{Name: "csv", Options: options})
+		assert.Error(t, err, "%v", options)
+	}
+}
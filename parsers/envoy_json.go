@@ -0,0 +1,131 @@
+package parsers
+
+// Parses Envoy access logs written as JSON, using the keys of Envoy's and
+// Istio's default JSON format, e.g.
+// {"start_time":"2024-03-01T12:00:00.123Z","method":"GET","path":"/","response_code":200,
+//  "response_flags":"-","duration":12,"upstream_host":"10.0.0.5:8080",...}
+// https://istio.io/latest/docs/tasks/observability/logs/access-log/
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Fields sent as integers. Envoy writes most of these as numbers, but some
+// (e.g. upstream_service_time, which comes from a header) as strings.
+var envoyIntFields = map[string]bool{
+	"response_code":                  true,
+	"bytes_received":                 true,
+	"bytes_sent":                     true,
+	"duration":                       true,
+	"request_duration":               true,
+	"response_duration":              true,
+	"response_tx_duration":           true,
+	"upstream_service_time":          true,
+	"upstream_request_attempt_count": true,
+}
+
+// The names of Envoy's response flags
+// https://www.envoyproxy.io/docs/envoy/latest/configuration/observability/access_log/usage#config-access-log-format-response-flags
+var envoyResponseFlags = map[string]string{
+	"UH":    "no_healthy_upstream",
+	"UF":    "upstream_connection_failure",
+	"UO":    "upstream_overflow",
+	"NR":    "no_route_found",
+	"URX":   "upstream_retry_limit_exceeded",
+	"NC":    "no_cluster_found",
+	"DT":    "duration_timeout",
+	"DC":    "downstream_connection_termination",
+	"LH":    "failed_local_healthcheck",
+	"UT":    "upstream_request_timeout",
+	"LR":    "local_reset",
+	"UR":    "upstream_remote_reset",
+	"UC":    "upstream_connection_termination",
+	"DI":    "delay_injected",
+	"FI":    "fault_injected",
+	"RL":    "rate_limited",
+	"UAEX":  "unauthorized_external_service",
+	"RLSE":  "rate_limit_service_error",
+	"IH":    "invalid_envoy_request_headers",
+	"SI":    "stream_idle_timeout",
+	"DPE":   "downstream_protocol_error",
+	"UPE":   "upstream_protocol_error",
+	"UMSDR": "upstream_max_stream_duration_reached",
+	"OM":    "overload_manager",
+	"DF":    "dns_resolution_failure",
+	"DO":    "drop_overload",
+}
+
+type EnvoyJSONParserFactory struct{}
+
+func (pf *EnvoyJSONParserFactory) Init(options map[string]interface{}) error { return nil }
+
+func (pf *EnvoyJSONParserFactory) New() Parser {
+	return &EnvoyJSONParser{json: &JSONParser{preserveIntegers: true}}
+}
+
+type EnvoyJSONParser struct {
+	json *JSONParser
+}
+
+func (p *EnvoyJSONParser) Parse(line string) (map[string]interface{}, error) {
+	data, err := p.json.Parse(line)
+	if err != nil {
+		return nil, err
+	}
+
+	for k, v := range data {
+		// Envoy writes "-" for values that aren't available
+		if v == nil || v == "-" {
+			delete(data, k)
+			continue
+		}
+		if s, ok := v.(string); ok && envoyIntFields[k] {
+			if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+				data[k] = i
+			}
+		}
+	}
+
+	if s, ok := data["start_time"].(string); ok {
+		if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			data["start_time"] = ts
+		}
+	}
+
+	if flags, ok := data["response_flags"].(string); ok {
+		for _, flag := range strings.Split(flags, ",") {
+			name, ok := envoyResponseFlags[flag]
+			if !ok {
+				name = flag
+			}
+			data["response_flag."+name] = true
+		}
+	}
+
+	if upstream, ok := data["upstream_host"].(string); ok {
+		if host, port, err := net.SplitHostPort(upstream); err == nil {
+			data["upstream_host_address"] = host
+			if i, err := strconv.Atoi(port); err == nil {
+				data["upstream_host_port"] = i
+			}
+		}
+	}
+
+	// Istio names clusters e.g. outbound|9080|v1|reviews.default.svc.cluster.local
+	if cluster, ok := data["upstream_cluster"].(string); ok {
+		if parts := strings.Split(cluster, "|"); len(parts) == 4 {
+			data["upstream_direction"] = parts[0]
+			if i, err := strconv.Atoi(parts[1]); err == nil {
+				data["upstream_service_port"] = i
+			}
+			if parts[2] != "" {
+				data["upstream_subset"] = parts[2]
+			}
+			data["upstream_service"] = parts[3]
+		}
+	}
+	return data, nil
+}
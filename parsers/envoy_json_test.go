@@ -0,0 +1,60 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvoyJSONParser(t *testing.T) {
+	for _, name := range []string{"envoy-json", "istio"} {
+		pf, err := NewParserFactory(&config.ParserConfig{Name: name})
+		assert.NoError(t, err)
+		parser := pf.New()
+
+		parsed, err := parser.Parse(`{"start_time":"2024-03-01T12:00:00.123Z","method":"GET","path":"/reviews/0","protocol":"HTTP/1.1","response_code":503,"response_flags":"UF,URX","route_name":"default","bytes_received":0,"bytes_sent":91,"duration":1002,"upstream_service_time":"1001","x_forwarded_for":"-","user_agent":"curl/8.0","request_id":"a1b2","authority":"reviews:9080","upstream_host":"10.0.0.5:9080","upstream_cluster":"outbound|9080|v1|reviews.default.svc.cluster.local","upstream_transport_failure_reason":null,"trace_id":"5b8efff798038103d269b633813fc60c"}`)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"start_time":     time.Date(2024, 3, 1, 12, 0, 0, 123000000, time.UTC),
+			"method":         "GET",
+			"path":           "/reviews/0",
+			"protocol":       "HTTP/1.1",
+			"response_code":  int64(503),
+			"response_flags": "UF,URX",
+			"response_flag.upstream_connection_failure":   true,
+			"response_flag.upstream_retry_limit_exceeded": true,
+			"route_name":            "default",
+			"bytes_received":        int64(0),
+			"bytes_sent":            int64(91),
+			"duration":              int64(1002),
+			"upstream_service_time": int64(1001),
+			"user_agent":            "curl/8.0",
+			"request_id":            "a1b2",
+			"authority":             "reviews:9080",
+			"upstream_host":         "10.0.0.5:9080",
+			"upstream_host_address": "10.0.0.5",
+			"upstream_host_port":    9080,
+			"upstream_cluster":      "outbound|9080|v1|reviews.default.svc.cluster.local",
+			"upstream_direction":    "outbound",
+			"upstream_service_port": 9080,
+			"upstream_subset":       "v1",
+			"upstream_service":      "reviews.default.svc.cluster.local",
+			"trace_id":              "5b8efff798038103d269b633813fc60c",
+		}, parsed)
+
+		parsed, err = parser.Parse(`{"response_code":200,"response_flags":"-","upstream_cluster":"outbound|80||web.default.svc.cluster.local","upstream_host":"-"}`)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"response_code":         int64(200),
+			"upstream_cluster":      "outbound|80||web.default.svc.cluster.local",
+			"upstream_direction":    "outbound",
+			"upstream_service_port": 80,
+			"upstream_service":      "web.default.svc.cluster.local",
+		}, parsed)
+
+		_, err = parser.Parse(`[2024-03-01T12:00:00.123Z] "GET / HTTP/1.1" 200`)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,130 @@
+package parsers
+
+// The fallback parser tries a list of parsers in order, and uses the result of
+// the first one that can handle a line. This is useful for applications that
+// mix structured logs with plain-text output such as startup banners or
+// panics.
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+)
+
+// The field that records which parser handled a line, unless overridden with
+// the `field` option.
+const defaultFallbackField = "meta.parser"
+
+type FallbackParserFactory struct {
+	configs   []*config.ParserConfig
+	factories []ParserFactory
+	field     string
+}
+
+func (pf *FallbackParserFactory) Init(options map[string]interface{}) error {
+	if len(pf.configs) == 0 {
+		return fmt.Errorf("fallback parser specified but no parsers defined")
+	}
+
+	pf.field = defaultFallbackField
+	if fieldOption, ok := options["field"]; ok {
+		typedFieldOption, ok := fieldOption.(string)
+		if !ok {
+			return fmt.Errorf("Unexpected type for field option (expected string, got %v)", reflect.TypeOf(fieldOption))
+		}
+		pf.field = typedFieldOption
+	}
+
+	pf.factories = make([]ParserFactory, len(pf.configs))
+	for i, parserConfig := range pf.configs {
+		if parserConfig == nil {
+			return fmt.Errorf("fallback parser %d is empty", i)
+		}
+		factory, err := NewParserFactory(parserConfig)
+		if err != nil {
+			return fmt.Errorf("Error setting up fallback parser %s: %v", parserConfig.Name, err)
+		}
+		// A parser that joins or remembers lines takes every line after its
+		// first match, so parsers after it in the chain would never see any.
+		if i < len(pf.configs)-1 && IsStateful(factory.New()) {
+			return fmt.Errorf("the %s parser joins or remembers lines, so it can only be the last fallback parser", parserConfig.Name)
+		}
+		pf.factories[i] = factory
+	}
+	return nil
+}
+
+func (pf *FallbackParserFactory) New() Parser {
+	parsers := make([]Parser, len(pf.factories))
+	names := make([]string, len(pf.factories))
+	for i, factory := range pf.factories {
+		// Each file gets its own instance of every parser in the chain, so a
+		// stateful parser at the end of it only sees that file's lines.
+		parsers[i] = factory.New()
+		names[i] = pf.configs[i].Name
+	}
+	return &FallbackParser{
+		parsers: parsers,
+		names:   names,
+		field:   pf.field,
+	}
+}
+
+type FallbackParser struct {
+	parsers []Parser
+	names   []string
+	field   string
+}
+
+func (p *FallbackParser) Stateful() bool {
+	for _, parser := range p.parsers {
+		if IsStateful(parser) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *FallbackParser) Holding() bool {
+	for _, parser := range p.parsers {
+		if fp, ok := parser.(FlushableParser); ok && fp.Holding() {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush returns the record held by the first parser in the chain that's
+// holding one.
+func (p *FallbackParser) Flush() map[string]interface{} {
+	for i, parser := range p.parsers {
+		if fp, ok := parser.(FlushableParser); ok {
+			if data := fp.Flush(); data != nil {
+				if p.field != "" {
+					data[p.field] = p.names[i]
+				}
+				return data
+			}
+		}
+	}
+	return nil
+}
+
+func (p *FallbackParser) Parse(line string) (map[string]interface{}, error) {
+	var err error
+	for i, parser := range p.parsers {
+		var data map[string]interface{}
+		data, err = parser.Parse(line)
+		if err != nil {
+			continue
+		}
+		// A nil result means the parser consumed the line without producing
+		// an event yet (e.g., it's waiting on a multi-line record).
+		if data != nil && p.field != "" {
+			data[p.field] = p.names[i]
+		}
+		return data, nil
+	}
+	return nil, fmt.Errorf("Couldn't parse line with any fallback parser: %v", err)
+}
@@ -0,0 +1,55 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFallbackParserMixedFormats(t *testing.T) {
+	pf, err := NewParserFactory(&config.ParserConfig{
+		Name:    "fallback",
+		Parsers: []*config.ParserConfig{{Name: "json"}, {Name: "java"}},
+	})
+	assert.NoError(t, err)
+	parser := pf.New()
+
+	lines := []string{
+		`2024-03-01 12:00:01.456 [main] ERROR c.e.App - Failed`,
+		"\tat com.example.App.main(App.java:10)",
+		`{"status": 200}`,
+		`2024-03-01 12:00:02.000 [main] INFO  c.e.App - done`,
+	}
+	var events []map[string]interface{}
+	for _, line := range lines {
+		parsed, err := parser.Parse(line)
+		assert.NoError(t, err)
+		if parsed != nil {
+			events = append(events, parsed)
+		}
+	}
+	assert.Equal(t, []map[string]interface{}{
+		{"status": float64(200), "meta.parser": "json"},
+		{
+			"timestamp":            time.Date(2024, 3, 1, 12, 0, 1, 456000000, time.UTC),
+			"thread":               "main",
+			"level":                "error",
+			"logger":               "c.e.App",
+			"message":              "Failed",
+			"exception.stacktrace": "\tat com.example.App.main(App.java:10)",
+			"meta.parser":          "java",
+		},
+	}, events)
+}
+
+func TestFallbackParserRejectsEarlyStatefulParsers(t *testing.T) {
+	for _, parsers := range [][]*config.ParserConfig{
+		{{Name: "java"}, {Name: "json"}},
+		{{Name: "klog"}, {Name: "nop"}},
+	} {
+		_, err := NewParserFactory(&config.ParserConfig{Name: "fallback", Parsers: parsers})
+		assert.Error(t, err, "%s", parsers[0].Name)
+	}
+}
@@ -0,0 +1,170 @@
+package parsers
+
+// grok is the pattern syntax used by Logstash. Expressions are regular
+// expressions that can refer to named patterns, e.g.
+// `%{IPORHOST:client} %{NUMBER:duration:float}`.
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Matches %{PATTERN}, %{PATTERN:field} and %{PATTERN:field:type}
+var grokReference = regexp.MustCompile(`%\{(\w+)(?::([^:}]+))?(?::(\w+))?\}`)
+
+// Guards against patterns that (directly or indirectly) refer to themselves
+const maxGrokDepth = 32
+
+type grokField struct {
+	name      string
+	valueType string
+}
+
+type grokExpression struct {
+	re *regexp.Regexp
+	// capture group index -> field
+	fields map[int]grokField
+}
+
+// grokCompiler expands grok references into an RE2 expression. Every named
+// reference becomes a uniquely named capture group, so that field names don't
+// have to be valid RE2 group names and the same field can appear in several
+// alternatives.
+type grokCompiler struct {
+	patterns map[string]string
+	fields   map[string]grokField
+	next     int
+}
+
+func (c *grokCompiler) compile(expr string) (*grokExpression, error) {
+	c.fields = make(map[string]grokField)
+	expanded, err := c.expand(expr, 0)
+	if err != nil {
+		return nil, err
+	}
+	re, err := regexp.Compile(expanded)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid grok expression `%s`: %v", expr, err)
+	}
+	ret := &grokExpression{re: re, fields: make(map[int]grokField)}
+	for i, name := range re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		if field, ok := c.fields[name]; ok {
+			ret.fields[i] = field
+		} else {
+			// A plain RE2 named group written directly in the expression
+			ret.fields[i] = grokField{name: name}
+		}
+	}
+	return ret, nil
+}
+
+func (c *grokCompiler) expand(expr string, depth int) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("grok patterns are nested too deeply in `%s` (is a pattern recursive?)", expr)
+	}
+	var err error
+	expanded := grokReference.ReplaceAllStringFunc(expr, func(ref string) string {
+		if err != nil {
+			return ""
+		}
+		m := grokReference.FindStringSubmatch(ref)
+		name, fieldName, valueType := m[1], m[2], m[3]
+		definition, ok := c.patterns[name]
+		if !ok {
+			err = fmt.Errorf("Unknown grok pattern %s", name)
+			return ""
+		}
+		var sub string
+		sub, err = c.expand(definition, depth+1)
+		if err != nil {
+			return ""
+		}
+		if fieldName == "" {
+			return "(?:" + sub + ")"
+		}
+		switch valueType {
+		case "", "string", "int", "float":
+		default:
+			err = fmt.Errorf("Unknown type %s for grok field %s (expected int, float or string)", valueType, fieldName)
+			return ""
+		}
+		group := fmt.Sprintf("grok%d", c.next)
+		c.next++
+		c.fields[group] = grokField{name: fieldName, valueType: valueType}
+		return "(?P<" + group + ">" + sub + ")"
+	})
+	return expanded, err
+}
+
+type GrokParserFactory struct {
+	expressions []*grokExpression
+}
+
+func (pf *GrokParserFactory) Init(options map[string]interface{}) error {
+	if options == nil {
+		return fmt.Errorf("grok parser specified but no options defined")
+	}
+
+	patterns := make(map[string]string, len(grokPatterns))
+	for k, v := range grokPatterns {
+		patterns[k] = v
+	}
+	definitions, err := stringMapOption(options, "patternDefinitions")
+	if err != nil {
+		return err
+	}
+	for name, definition := range definitions {
+		patterns[name] = definition
+	}
+
+	expressions, ok := options["expressions"].([]interface{})
+	if !ok || len(expressions) == 0 {
+		return fmt.Errorf("grok parser missing expressions option")
+	}
+
+	compiler := &grokCompiler{patterns: patterns}
+	pf.expressions = make([]*grokExpression, len(expressions))
+	for i, s := range expressions {
+		expression, ok := s.(string)
+		if !ok {
+			return fmt.Errorf("expected expression %v to be string", s)
+		}
+		compiled, err := compiler.compile(expression)
+		if err != nil {
+			return err
+		}
+		pf.expressions[i] = compiled
+	}
+	return nil
+}
+
+func (pf *GrokParserFactory) New() Parser {
+	// Compiled regexps are safe to share between parser instances
+	return &GrokParser{expressions: pf.expressions}
+}
+
+type GrokParser struct {
+	expressions []*grokExpression
+}
+
+func (p *GrokParser) Parse(line string) (map[string]interface{}, error) {
+	for _, expr := range p.expressions {
+		match := expr.re.FindStringSubmatchIndex(line)
+		if match == nil {
+			continue
+		}
+		ret := make(map[string]interface{}, len(expr.fields))
+		for i, field := range expr.fields {
+			if match[2*i] < 0 {
+				// group didn't participate in the match
+				continue
+			}
+			ret[field.name] = convertValue(line[match[2*i]:match[2*i+1]], field.valueType)
+		}
+		return ret, nil
+	}
+	return nil, fmt.Errorf("Couldn't parse line with any supplied grok expressions: %s", line)
+}
@@ -0,0 +1,93 @@
+package parsers
+
+import (
+	"testing"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGrokPatternLibraryCompiles(t *testing.T) {
+	compiler := &grokCompiler{patterns: grokPatterns}
+	for name := range grokPatterns {
+		_, err := compiler.compile("%{" + name + ":value}")
+		assert.NoError(t, err, name)
+	}
+}
+
+func TestGrokParser(t *testing.T) {
+	cfg := &config.ParserConfig{
+		Name: "grok", Options: map[string]interface{}{
+			"expressions": []interface{}{
+				"%{COMBINEDAPACHELOG}",
+				`^%{TIMESTAMP_ISO8601:time} %{LOGLEVEL:level} \[%{APPNAME:app}\] took %{NUMBER:duration_ms:float}ms status=%{INT:status:int}`,
+			},
+			"patternDefinitions": map[interface{}]interface{}{
+				"APPNAME": `[a-z][a-z0-9-]*`,
+			},
+		},
+	}
+
+	pf, err := NewParserFactory(cfg)
+	assert.NoError(t, err)
+	parser := pf.New()
+
+	tc := []struct {
+		line     string
+		expected map[string]interface{}
+		err      bool
+	}{
+		{
+			line: `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"`,
+			expected: map[string]interface{}{
+				"clientip":    "127.0.0.1",
+				"ident":       "-",
+				"auth":        "frank",
+				"timestamp":   "10/Oct/2000:13:55:36 -0700",
+				"verb":        "GET",
+				"request":     "/apache_pb.gif",
+				"httpversion": "1.0",
+				"response":    "200",
+				"bytes":       "2326",
+				"referrer":    `"http://www.example.com/start.html"`,
+				"agent":       `"Mozilla/4.08"`,
+			},
+		},
+		{
+			line: `2024-03-01T12:00:00Z WARN [billing-api] took 12.5ms status=503`,
+			expected: map[string]interface{}{
+				"time":        "2024-03-01T12:00:00Z",
+				"level":       "WARN",
+				"app":         "billing-api",
+				"duration_ms": 12.5,
+				"status":      int64(503),
+			},
+		},
+		{line: "the quick brown fox jumped over the lazy dog", expected: nil, err: true},
+	}
+
+	for _, tt := range tc {
+		parsed, err := parser.Parse(tt.line)
+		assert.Equal(t, tt.expected, parsed)
+		if tt.err {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestGrokParserInvalidConfig(t *testing.T) {
+	testcases := []map[string]interface{}{
+		nil,
+		{"expressions": []interface{}{}},
+		{"expressions": []interface{}{"%{NOSUCHPATTERN:x}"}},
+		{"expressions": []interface{}{"%{INT:x:bogus}"}},
+		{"expressions": []interface{}{"%{LOOP}"}, "patternDefinitions": map[string]interface{}{"LOOP": "a%{LOOP}"}},
+		{"expressions": []interface{}{"%{WORD:x}("}},
+	}
+	for _, options := range testcases {
+		_, err := NewParserFactory(&config.ParserConfig{Name: "grok", Options: options})
+		assert.Error(t, err, "%v", options)
+	}
+}
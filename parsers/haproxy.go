@@ -0,0 +1,145 @@
+package parsers
+
+// Parses HAProxy's HTTP log format (`option httplog`), e.g.
+// 10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1 10/0/30/69/109 200 2750 - - ---- 1/1/1/1/0 0/0 {1wt.eu} {} "GET /index.html HTTP/1.1"
+// optionally preceded by a syslog header. See
+// https://docs.haproxy.org/2.8/configuration.html#8.2.3
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var haproxyLine = regexp.MustCompile(`^(?:.*?haproxy\[\d+\]: )?` +
+	`(?P<client_ip>\S+):(?P<client_port>\d+) \[(?P<accept_date>[^\]]+)\] ` +
+	`(?P<frontend_name>\S+) (?P<backend_name>[^/\s]+)/(?P<server_name>\S+) ` +
+	`(?P<timers>[-+0-9]+(?:/[-+0-9]+){4}) (?P<status>-?\d+) (?P<bytes_read>\+?\d+) ` +
+	`(?P<captured_request_cookie>\S+) (?P<captured_response_cookie>\S+) (?P<termination_state>\S+) ` +
+	`(?P<connections>[+0-9]+(?:/[+0-9]+){4}) (?P<queues>\d+/\d+)` +
+	`(?: \{(?P<captured_request_headers>[^}]*)\})?(?: \{(?P<captured_response_headers>[^}]*)\})?` +
+	`(?: "(?P<request>[^"]*)"?)?$`)
+
+// The five timers, in milliseconds. Older versions log Tq/Tw/Tc/Tr/Tt, newer
+// ones TR/Tw/Tc/Tr/Ta, but they're in the same places.
+var haproxyTimers = []string{"time_request_ms", "time_queue_ms", "time_connect_ms", "time_response_ms", "time_total_ms"}
+
+var haproxyConnections = []string{"actconn", "feconn", "beconn", "srv_conn", "retries"}
+
+var haproxyQueues = []string{"srv_queue", "backend_queue"}
+
+// The first character of the termination state: what ended the session
+var haproxyTerminationCauses = map[byte]string{
+	'C': "client_abort",
+	'S': "server_abort",
+	'P': "proxy_abort",
+	'L': "local_response",
+	'R': "resource_exhausted",
+	'I': "internal_error",
+	'D': "server_down",
+	'U': "server_up",
+	'K': "admin_killed",
+	'c': "client_timeout",
+	's': "server_timeout",
+}
+
+// The second character of the termination state: what the session was doing
+// when it ended
+var haproxyTerminationPhases = map[byte]string{
+	'R': "request",
+	'Q': "queue",
+	'C': "connect",
+	'H': "headers",
+	'D': "data",
+	'L': "last_data",
+	'T': "tarpit",
+}
+
+type HAProxyParserFactory struct{}
+
+func (pf *HAProxyParserFactory) Init(options map[string]interface{}) error { return nil }
+
+func (pf *HAProxyParserFactory) New() Parser {
+	return &HAProxyParser{}
+}
+
+type HAProxyParser struct{}
+
+func (p *HAProxyParser) Parse(line string) (map[string]interface{}, error) {
+	match := haproxyLine.FindStringSubmatch(line)
+	if match == nil {
+		return nil, fmt.Errorf("Couldn't parse line as HAProxy log line: %s", line)
+	}
+
+	ret := make(map[string]interface{})
+	for i, name := range haproxyLine.SubexpNames() {
+		value := match[i]
+		if i == 0 || value == "" || value == "-" {
+			continue
+		}
+		switch name {
+		case "timers":
+			setHAProxyInts(ret, haproxyTimers, value)
+		case "connections":
+			setHAProxyInts(ret, haproxyConnections, value)
+		case "queues":
+			setHAProxyInts(ret, haproxyQueues, value)
+		case "client_port", "status", "bytes_read":
+			setHAProxyInts(ret, []string{name}, value)
+		case "accept_date":
+			if ts, err := time.Parse("02/Jan/2006:15:04:05.000", value); err == nil {
+				ret[name] = ts
+			} else {
+				ret[name] = value
+			}
+		case "termination_state":
+			ret[name] = value
+			if cause, ok := haproxyTerminationCauses[value[0]]; ok {
+				ret["termination_cause"] = cause
+			}
+			if len(value) > 1 {
+				if phase, ok := haproxyTerminationPhases[value[1]]; ok {
+					ret["termination_phase"] = phase
+				}
+			}
+		case "request":
+			ret[name] = value
+			splitRequestLine(ret, value)
+		default:
+			ret[name] = value
+		}
+	}
+	return ret, nil
+}
+
+// setHAProxyInts sets each of names to the corresponding value in the
+// slash-separated list values. A leading + (which marks a value that was
+// logged before it was final, or a retry that was redispatched) is dropped.
+func setHAProxyInts(ret map[string]interface{}, names []string, values string) {
+	for i, value := range strings.Split(values, "/") {
+		if i >= len(names) {
+			break
+		}
+		if n, err := strconv.ParseInt(strings.TrimPrefix(value, "+"), 10, 64); err == nil {
+			ret[names[i]] = n
+		} else {
+			ret[names[i]] = value
+		}
+	}
+}
+
+// splitRequestLine adds the method, URI and protocol of an HTTP request line
+// such as `GET /index.html HTTP/1.1`.
+func splitRequestLine(ret map[string]interface{}, request string) {
+	parts := strings.SplitN(request, " ", 3)
+	if len(parts) < 2 {
+		return
+	}
+	ret["request_method"] = parts[0]
+	ret["request_uri"] = parts[1]
+	if len(parts) == 3 {
+		ret["request_protocol"] = parts[2]
+	}
+}
@@ -0,0 +1,62 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHAProxyParser(t *testing.T) {
+	pf, err := NewParserFactory(&config.ParserConfig{Name: "haproxy"})
+	assert.NoError(t, err)
+	parser := pf.New()
+
+	parsed, err := parser.Parse(`Feb  6 12:14:14 localhost haproxy[14389]: 10.0.1.2:33317 [06/Feb/2009:12:14:14.655] http-in static/srv1 10/0/30/69/+109 200 2750 - - ---- 1/1/1/1/+1 0/0 {1wt.eu} {} "GET /index.html HTTP/1.1"`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"client_ip":                "10.0.1.2",
+		"client_port":              int64(33317),
+		"accept_date":              time.Date(2009, 2, 6, 12, 14, 14, 655000000, time.UTC),
+		"frontend_name":            "http-in",
+		"backend_name":             "static",
+		"server_name":              "srv1",
+		"time_request_ms":          int64(10),
+		"time_queue_ms":            int64(0),
+		"time_connect_ms":          int64(30),
+		"time_response_ms":         int64(69),
+		"time_total_ms":            int64(109),
+		"status":                   int64(200),
+		"bytes_read":               int64(2750),
+		"termination_state":        "----",
+		"actconn":                  int64(1),
+		"feconn":                   int64(1),
+		"beconn":                   int64(1),
+		"srv_conn":                 int64(1),
+		"retries":                  int64(1),
+		"srv_queue":                int64(0),
+		"backend_queue":            int64(0),
+		"captured_request_headers": "1wt.eu",
+		"request":                  "GET /index.html HTTP/1.1",
+		"request_method":           "GET",
+		"request_uri":              "/index.html",
+		"request_protocol":         "HTTP/1.1",
+	}, parsed)
+
+	// Without a syslog header or captured headers, and aborted by the client
+	// while waiting for the response.
+	parsed, err = parser.Parse(`[2001:db8::1]:443 [01/Mar/2024:12:00:00.000] fe~ be/<NOSRV> 5/-1/-1/-1/5003 503 217 - - CHNN 3/2/0/0/0 0/0 "POST /upload HTTP/2.0"`)
+	assert.NoError(t, err)
+	assert.Equal(t, "[2001:db8::1]", parsed["client_ip"])
+	assert.Equal(t, int64(-1), parsed["time_queue_ms"])
+	assert.Equal(t, int64(5003), parsed["time_total_ms"])
+	assert.Equal(t, "client_abort", parsed["termination_cause"])
+	assert.Equal(t, "headers", parsed["termination_phase"])
+	assert.Equal(t, "<NOSRV>", parsed["server_name"])
+	assert.Equal(t, "POST", parsed["request_method"])
+	assert.NotContains(t, parsed, "captured_request_headers")
+
+	_, err = parser.Parse(`10.0.1.2 - - [06/Feb/2009:12:14:14 +0000] "GET / HTTP/1.1" 200 2750`)
+	assert.Error(t, err)
+}
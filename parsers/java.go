@@ -0,0 +1,393 @@
+package parsers
+
+// Parses logs written by Java logging libraries such as logback and log4j,
+// using the pattern layout they were written with, e.g.
+// %d{yyyy-MM-dd HH:mm:ss.SSS} [%thread] %-5level %logger{36} - %msg%n
+// Exceptions are written on the lines following the message, and are folded
+// into the same event.
+// https://logback.qos.ch/manual/layouts.html#ClassicPatternLayout
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// logback's documented example layout
+const defaultJavaPattern = `%d{yyyy-MM-dd HH:mm:ss.SSS} [%thread] %-5level %logger{36} - %msg%n`
+
+// Matches %%, or a conversion with optional format modifiers and options,
+// e.g. %-5level or %logger{36}
+var javaConversion = regexp.MustCompile(`%(?:(%)|(-?[0-9]*(?:\.-?[0-9]+)?)([a-zA-Z]+)((?:\{[^}]*\})*))`)
+
+type javaField struct {
+	name    string
+	pattern string
+	isInt   bool
+}
+
+var javaConversions = map[string]javaField{
+	"t":        {name: "thread", pattern: `.*?`},
+	"thread":   {name: "thread", pattern: `.*?`},
+	"p":        {name: "level", pattern: `[A-Za-z]+`},
+	"le":       {name: "level", pattern: `[A-Za-z]+`},
+	"level":    {name: "level", pattern: `[A-Za-z]+`},
+	"c":        {name: "logger", pattern: `\S*`},
+	"lo":       {name: "logger", pattern: `\S*`},
+	"logger":   {name: "logger", pattern: `\S*`},
+	"C":        {name: "class", pattern: `\S*`},
+	"class":    {name: "class", pattern: `\S*`},
+	"M":        {name: "method", pattern: `\S*`},
+	"method":   {name: "method", pattern: `\S*`},
+	"F":        {name: "file", pattern: `\S*`},
+	"file":     {name: "file", pattern: `\S*`},
+	"L":        {name: "line", pattern: `\d+|\?`, isInt: true},
+	"line":     {name: "line", pattern: `\d+|\?`, isInt: true},
+	"m":        {name: "message", pattern: `.*`},
+	"msg":      {name: "message", pattern: `.*`},
+	"message":  {name: "message", pattern: `.*`},
+	"r":        {name: "relative_ms", pattern: `\d+`, isInt: true},
+	"relative": {name: "relative_ms", pattern: `\d+`, isInt: true},
+	"pid":      {name: "pid", pattern: `\d+`, isInt: true},
+	"T":        {name: "thread_id", pattern: `\d+`, isInt: true},
+	"tid":      {name: "thread_id", pattern: `\d+`, isInt: true},
+	"threadId": {name: "thread_id", pattern: `\d+`, isInt: true},
+	"marker":   {name: "marker", pattern: `.*?`},
+	"X":        {name: "mdc", pattern: `.*?`},
+	"mdc":      {name: "mdc", pattern: `.*?`},
+}
+
+// Conversions that write nothing on the first line of an event
+var javaEmptyConversions = map[string]bool{
+	"n": true, "ex": true, "exception": true, "throwable": true,
+	"xEx": true, "xException": true, "xThrowable": true,
+	"rEx": true, "rException": true, "rThrowable": true,
+	"wEx": true, "nopex": true, "nopexception": true,
+}
+
+// Named date formats
+var javaDateFormats = map[string]string{
+	"":         "yyyy-MM-dd HH:mm:ss,SSS",
+	"DEFAULT":  "yyyy-MM-dd HH:mm:ss,SSS",
+	"ISO8601":  "yyyy-MM-dd HH:mm:ss,SSS",
+	"ABSOLUTE": "HH:mm:ss,SSS",
+	"DATE":     "dd MMM yyyy HH:mm:ss,SSS",
+}
+
+var (
+	javaStackFrame      = regexp.MustCompile(`^\s+(?:at |\.\.\. \d+ (?:more|common frames omitted))`)
+	javaCause           = regexp.MustCompile(`^\s*(?:Caused by|Suppressed): `)
+	javaExceptionHeader = regexp.MustCompile(`^(?:Exception in thread "[^"]*" )?([a-zA-Z_$][\w$]*(?:\.[a-zA-Z_$][\w$]*)*(?:Exception|Error|Throwable))(?:: (.*))?$`)
+)
+
+type JavaParserFactory struct {
+	re     *regexp.Regexp
+	fields []javaField
+	layout string
+}
+
+func (pf *JavaParserFactory) Init(options map[string]interface{}) error {
+	pattern := defaultJavaPattern
+	if patternOption, ok := options["pattern"]; ok {
+		typedPatternOption, ok := patternOption.(string)
+		if !ok {
+			return fmt.Errorf("Unexpected type for pattern option (expected string, got %v)", reflect.TypeOf(patternOption))
+		}
+		pattern = typedPatternOption
+	}
+	return pf.compile(pattern)
+}
+
+// compile translates a pattern layout into a regular expression. Groups are
+// numbered rather than named, since field names such as mdc.user_id aren't
+// valid group names.
+func (pf *JavaParserFactory) compile(pattern string) error {
+	var expr strings.Builder
+	expr.WriteString("^")
+	prev := 0
+	for _, loc := range javaConversion.FindAllStringSubmatchIndex(pattern, -1) {
+		expr.WriteString(regexp.QuoteMeta(pattern[prev:loc[0]]))
+		prev = loc[1]
+		if loc[2] != -1 {
+			expr.WriteString("%")
+			continue
+		}
+		padding := pattern[loc[4]:loc[5]]
+		word := pattern[loc[6]:loc[7]]
+		option := strings.TrimSuffix(strings.TrimPrefix(pattern[loc[8]:loc[9]], "{"), "}")
+		if i := strings.Index(option, "}{"); i != -1 {
+			option = option[:i]
+		}
+
+		var field javaField
+		switch {
+		case javaEmptyConversions[word]:
+			continue
+		case word == "d" || word == "date":
+			dateFormat, ok := javaDateFormats[option]
+			if !ok {
+				dateFormat = option
+			}
+			datePattern, layout, err := javaDateFormatToRegexp(dateFormat)
+			if err != nil {
+				return err
+			}
+			field = javaField{name: "timestamp", pattern: datePattern}
+			pf.layout = layout
+		default:
+			var ok bool
+			field, ok = javaConversions[word]
+			if !ok {
+				return fmt.Errorf("Unsupported conversion %%%s in Java log pattern", word)
+			}
+			if field.name == "mdc" && option != "" {
+				field.name = "mdc." + option
+			}
+		}
+
+		group := "(" + field.pattern + ")"
+		switch {
+		case strings.HasPrefix(padding, "-"):
+			group += " *"
+		case padding != "" && !strings.HasPrefix(padding, "."):
+			group = " *" + group
+		}
+		expr.WriteString(group)
+		pf.fields = append(pf.fields, field)
+	}
+	expr.WriteString(regexp.QuoteMeta(pattern[prev:]))
+	expr.WriteString("$")
+
+	re, err := regexp.Compile(expr.String())
+	if err != nil {
+		return fmt.Errorf("Invalid Java log pattern `%s`: %v", pattern, err)
+	}
+	pf.re = re
+	return nil
+}
+
+// javaDateFormatToRegexp translates a SimpleDateFormat pattern into a regular
+// expression and a layout for time.Parse. UNIX and UNIX_MILLIS are epoch
+// timestamps, and have an empty layout.
+func javaDateFormatToRegexp(format string) (string, string, error) {
+	if format == "UNIX" || format == "UNIX_MILLIS" {
+		return `\d+`, format, nil
+	}
+	var expr, layout strings.Builder
+	for i := 0; i < len(format); {
+		c := format[i]
+		if c == '\'' {
+			end := strings.IndexByte(format[i+1:], '\'')
+			if end == -1 {
+				return "", "", fmt.Errorf("Unterminated quote in date format `%s`", format)
+			}
+			literal := format[i+1 : i+1+end]
+			if literal == "" {
+				literal = "'"
+			}
+			expr.WriteString(regexp.QuoteMeta(literal))
+			layout.WriteString(literal)
+			i += end + 2
+			continue
+		}
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+			layout.WriteByte(c)
+			i++
+			continue
+		}
+		n := 1
+		for i+n < len(format) && format[i+n] == c {
+			n++
+		}
+		pattern, goLayout := javaDateField(c, n)
+		if pattern == "" {
+			return "", "", fmt.Errorf("Unsupported field %s in date format `%s`", format[i:i+n], format)
+		}
+		if c == 'S' && (i == 0 || (format[i-1] != '.' && format[i-1] != ',')) {
+			return "", "", fmt.Errorf("Fractional seconds must follow . or , in date format `%s`", format)
+		}
+		expr.WriteString(pattern)
+		layout.WriteString(goLayout)
+		i += n
+	}
+	return expr.String(), layout.String(), nil
+}
+
+// javaDateField returns the regular expression and time.Parse layout for a
+// run of n copies of the SimpleDateFormat letter c.
+func javaDateField(c byte, n int) (string, string) {
+	switch c {
+	case 'y':
+		if n == 2 {
+			return `\d{2}`, "06"
+		}
+		return `\d{4}`, "2006"
+	case 'M':
+		switch n {
+		case 1:
+			return `\d{1,2}`, "1"
+		case 2:
+			return `\d{2}`, "01"
+		case 3:
+			return `[A-Za-z]{3}`, "Jan"
+		default:
+			return `[A-Za-z]+`, "January"
+		}
+	case 'd':
+		if n == 1 {
+			return `\d{1,2}`, "2"
+		}
+		return `\d{2}`, "02"
+	case 'H':
+		return `\d{1,2}`, "15"
+	case 'h':
+		if n == 1 {
+			return `\d{1,2}`, "3"
+		}
+		return `\d{2}`, "03"
+	case 'm':
+		if n == 1 {
+			return `\d{1,2}`, "4"
+		}
+		return `\d{2}`, "04"
+	case 's':
+		if n == 1 {
+			return `\d{1,2}`, "5"
+		}
+		return `\d{2}`, "05"
+	case 'S':
+		return fmt.Sprintf(`\d{%d}`, n), strings.Repeat("0", n)
+	case 'a':
+		return `[AaPp][Mm]`, "PM"
+	case 'E':
+		if n <= 3 {
+			return `[A-Za-z]{3}`, "Mon"
+		}
+		return `[A-Za-z]+`, "Monday"
+	case 'Z':
+		return `[+-]\d{4}`, "-0700"
+	case 'X':
+		switch n {
+		case 1:
+			return `(?:Z|[+-]\d{2})`, "Z07"
+		case 2:
+			return `(?:Z|[+-]\d{4})`, "Z0700"
+		default:
+			return `(?:Z|[+-]\d{2}:\d{2})`, "Z07:00"
+		}
+	case 'z':
+		return `[A-Za-z]+`, "MST"
+	}
+	return "", ""
+}
+
+func (pf *JavaParserFactory) New() Parser {
+	return &JavaParser{re: pf.re, fields: pf.fields, layout: pf.layout}
+}
+
+// JavaParser is stateful: a message can be followed by more lines of the
+// message or by an exception's stack trace, so each event is held until the
+// next one starts.
+type JavaParser struct {
+	re     *regexp.Regexp
+	fields []javaField
+	layout string
+
+	pending    map[string]interface{}
+	stacktrace []string
+}
+
+func (p *JavaParser) Stateful() bool { return true }
+
+func (p *JavaParser) Parse(line string) (map[string]interface{}, error) {
+	match := p.re.FindStringSubmatch(line)
+	if match == nil {
+		if p.pending == nil {
+			return nil, fmt.Errorf("Couldn't parse line as Java log line: %s", line)
+		}
+		p.continuation(line)
+		return nil, nil
+	}
+
+	ret := make(map[string]interface{}, len(p.fields))
+	for i, field := range p.fields {
+		value := match[i+1]
+		switch {
+		case field.isInt:
+			if n, err := strconv.Atoi(value); err == nil {
+				ret[field.name] = n
+			}
+		case field.name == "timestamp":
+			ret[field.name] = p.parseTimestamp(value)
+		case field.name == "level":
+			ret[field.name] = strings.ToLower(value)
+		case value != "":
+			ret[field.name] = value
+		}
+	}
+
+	prior := p.finish()
+	p.pending = ret
+	return prior, nil
+}
+
+func (p *JavaParser) Holding() bool { return p.pending != nil }
+
+func (p *JavaParser) Flush() map[string]interface{} {
+	return p.finish()
+}
+
+// continuation adds a line that follows the first line of an event, either to
+// the exception or to the message.
+func (p *JavaParser) continuation(line string) {
+	if len(p.stacktrace) > 0 || javaStackFrame.MatchString(line) || javaCause.MatchString(line) {
+		p.stacktrace = append(p.stacktrace, line)
+		return
+	}
+	if m := javaExceptionHeader.FindStringSubmatch(line); m != nil {
+		p.pending["exception.type"] = m[1]
+		if m[2] != "" {
+			p.pending["exception.message"] = m[2]
+		}
+		p.stacktrace = append(p.stacktrace, line)
+		return
+	}
+	message, _ := p.pending["message"].(string)
+	p.pending["message"] = message + "\n" + line
+}
+
+func (p *JavaParser) finish() map[string]interface{} {
+	ret := p.pending
+	if ret != nil && len(p.stacktrace) > 0 {
+		ret["exception.stacktrace"] = strings.Join(p.stacktrace, "\n")
+	}
+	p.pending = nil
+	p.stacktrace = nil
+	return ret
+}
+
+func (p *JavaParser) parseTimestamp(value string) interface{} {
+	switch p.layout {
+	case "UNIX", "UNIX_MILLIS":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return value
+		}
+		if p.layout == "UNIX" {
+			return time.Unix(n, 0).UTC()
+		}
+		return time.UnixMilli(n).UTC()
+	}
+	// Times without a date are sent as they are
+	if !strings.Contains(p.layout, "06") {
+		return value
+	}
+	if ts, err := time.Parse(p.layout, value); err == nil {
+		return ts
+	}
+	return value
+}
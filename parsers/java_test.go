@@ -0,0 +1,124 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newJavaParser(t *testing.T, options map[string]interface{}) Parser {
+	pf, err := NewParserFactory(&config.ParserConfig{Name: "java", Options: options})
+	assert.NoError(t, err)
+	return pf.New()
+}
+
+func TestJavaParser(t *testing.T) {
+	parser := newJavaParser(t, nil)
+
+	lines := []string{
+		`2024-03-01 12:00:00.123 [http-nio-8080-exec-1] INFO  c.e.orders.OrderController - Created order 42`,
+		`2024-03-01 12:00:01.456 [http-nio-8080-exec-2] ERROR c.e.orders.OrderController - Failed to create order`,
+		`java.lang.IllegalStateException: inventory unavailable: sku=123`,
+		"\tat com.example.orders.OrderService.create(OrderService.java:57)",
+		"\tat com.example.orders.OrderController.post(OrderController.java:31)",
+		`Caused by: java.net.SocketTimeoutException: Read timed out`,
+		"\tat java.base/java.net.SocketInputStream.read(SocketInputStream.java:168)",
+		"\t... 12 common frames omitted",
+		`2024-03-01 12:00:02.000 [main] WARN  c.e.Config - Deprecated settings:`,
+		`  cache.size`,
+		`2024-03-01 12:00:03.000 [main] DEBUG c.e.Config - done`,
+	}
+	var events []map[string]interface{}
+	for _, line := range lines {
+		parsed, err := parser.Parse(line)
+		assert.NoError(t, err)
+		if parsed != nil {
+			events = append(events, parsed)
+		}
+	}
+
+	assert.Equal(t, []map[string]interface{}{
+		{
+			"timestamp": time.Date(2024, 3, 1, 12, 0, 0, 123000000, time.UTC),
+			"thread":    "http-nio-8080-exec-1",
+			"level":     "info",
+			"logger":    "c.e.orders.OrderController",
+			"message":   "Created order 42",
+		},
+		{
+			"timestamp":         time.Date(2024, 3, 1, 12, 0, 1, 456000000, time.UTC),
+			"thread":            "http-nio-8080-exec-2",
+			"level":             "error",
+			"logger":            "c.e.orders.OrderController",
+			"message":           "Failed to create order",
+			"exception.type":    "java.lang.IllegalStateException",
+			"exception.message": "inventory unavailable: sku=123",
+			"exception.stacktrace": "java.lang.IllegalStateException: inventory unavailable: sku=123\n" +
+				"\tat com.example.orders.OrderService.create(OrderService.java:57)\n" +
+				"\tat com.example.orders.OrderController.post(OrderController.java:31)\n" +
+				"Caused by: java.net.SocketTimeoutException: Read timed out\n" +
+				"\tat java.base/java.net.SocketInputStream.read(SocketInputStream.java:168)\n" +
+				"\t... 12 common frames omitted",
+		},
+		{
+			"timestamp": time.Date(2024, 3, 1, 12, 0, 2, 0, time.UTC),
+			"thread":    "main",
+			"level":     "warn",
+			"logger":    "c.e.Config",
+			"message":   "Deprecated settings:\n  cache.size",
+		},
+	}, events)
+
+	_, err := newJavaParser(t, nil).Parse("  at nothing")
+	assert.Error(t, err)
+}
+
+func TestJavaParserPatterns(t *testing.T) {
+	parser := newJavaParser(t, map[string]interface{}{
+		"pattern": `%d{ISO8601} %5p %X{requestId} [%t] %C{1}.%M(%F:%L) %pid - %m%n%throwable`,
+	})
+	parsed, err := parser.Parse(`2024-03-01 12:00:00,250  WARN abc-123 [pool-1 thread-2] Worker.run(Worker.java:88) 7 - slow job`)
+	assert.NoError(t, err)
+	assert.Nil(t, parsed)
+	parsed, err = parser.Parse(`2024-03-01 12:00:01,000 ERROR  [main] Main.main(Main.java:1) 7 - crash`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"timestamp":     time.Date(2024, 3, 1, 12, 0, 0, 250000000, time.UTC),
+		"level":         "warn",
+		"mdc.requestId": "abc-123",
+		"thread":        "pool-1 thread-2",
+		"class":         "Worker",
+		"method":        "run",
+		"file":          "Worker.java",
+		"line":          88,
+		"pid":           7,
+		"message":       "slow job",
+	}, parsed)
+
+	parser = newJavaParser(t, map[string]interface{}{
+		"pattern": `%d{yyyy-MM-dd'T'HH:mm:ss.SSSXXX} %-5level %logger - %msg%n`,
+	})
+	parser.Parse(`2024-03-01T12:00:00.000+02:00 INFO  app - started`)
+	parsed, _ = parser.Parse(`2024-03-01T12:00:01.000Z INFO  app - next`)
+	assert.Equal(t, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC), parsed["timestamp"].(time.Time).UTC())
+
+	parser = newJavaParser(t, map[string]interface{}{"pattern": `%d{HH:mm:ss.SSS} %msg%n`})
+	parser.Parse(`12:00:00.000 started`)
+	parsed, _ = parser.Parse(`12:00:01.000 next`)
+	assert.Equal(t, "12:00:00.000", parsed["timestamp"])
+}
+
+func TestJavaParserInvalidPatterns(t *testing.T) {
+	for _, pattern := range []interface{}{
+		`%d %highlight{%level} %m`,
+		`%d{yyyy-MM-dd HH:mm:ssSSS} %m`,
+		`%d{yyyy-MM-dd'T} %m`,
+		`%d{GGGG} %m`,
+		42,
+	} {
+		_, err := NewParserFactory(&config.ParserConfig{Name: "java", Options: map[string]interface{}{"pattern": pattern}})
+		assert.Error(t, err, "pattern %v", pattern)
+	}
+}
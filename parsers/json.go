@@ -1,23 +1,174 @@
 package parsers
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// The field that records how many fields were dropped by the maxFields option
+const truncatedFieldsField = "meta.truncated_fields"
 
 // Parses line as JSON
-type JSONParser struct{}
+type JSONParser struct {
+	flattenDepth     int
+	separator        string
+	preserveIntegers bool
+	maxFields        int
+}
 
 func (p *JSONParser) Parse(line string) (map[string]interface{}, error) {
 	data := make(map[string]interface{})
-	err := json.Unmarshal([]byte(line), &data)
-	if err != nil {
-		return nil, err
+	if p.preserveIntegers {
+		decoder := json.NewDecoder(strings.NewReader(line))
+		decoder.UseNumber()
+		if err := decoder.Decode(&data); err != nil {
+			return nil, err
+		}
+		// More() is false before a stray closing bracket, so check that
+		// nothing at all follows the object
+		if _, err := decoder.Token(); err != io.EOF {
+			return nil, fmt.Errorf("invalid character after top-level value")
+		}
+		convertJSONNumbers(data)
+	} else {
+		err := json.Unmarshal([]byte(line), &data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.flattenDepth > 0 {
+		flattened := make(map[string]interface{}, len(data))
+		p.flatten(flattened, "", data, 1)
+		data = flattened
+	}
+	if p.maxFields > 0 && len(data) > p.maxFields {
+		data = truncateFields(data, p.maxFields)
 	}
 	return data, nil
 }
 
-type JSONParserFactory struct{}
+// flatten copies the fields of obj into dst, prefixing each key with its
+// parent's. Objects nested up to flattenDepth levels deep are flattened into
+// their parent; more deeply nested objects, and arrays, are sent as JSON
+// strings.
+func (p *JSONParser) flatten(dst map[string]interface{}, prefix string, obj map[string]interface{}, depth int) {
+	for k, v := range obj {
+		key := prefix + k
+		switch typed := v.(type) {
+		case map[string]interface{}:
+			if depth < p.flattenDepth {
+				p.flatten(dst, key+p.separator, typed, depth+1)
+			} else {
+				dst[key] = stringifyJSON(typed)
+			}
+		case []interface{}:
+			dst[key] = stringifyJSON(typed)
+		default:
+			dst[key] = v
+		}
+	}
+}
+
+func stringifyJSON(v interface{}) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// convertJSONNumbers replaces json.Number values with an int64 where the
+// number is an integer that fits, and a float64 otherwise.
+func convertJSONNumbers(v interface{}) interface{} {
+	switch typed := v.(type) {
+	case json.Number:
+		if i, err := typed.Int64(); err == nil {
+			return i
+		}
+		if f, err := typed.Float64(); err == nil {
+			return f
+		}
+		return typed.String()
+	case map[string]interface{}:
+		for k, child := range typed {
+			typed[k] = convertJSONNumbers(child)
+		}
+	case []interface{}:
+		for i, child := range typed {
+			typed[i] = convertJSONNumbers(child)
+		}
+	}
+	return v
+}
+
+// truncateFields keeps the first maxFields fields, in key order so that the
+// same fields are kept from one event to the next, and records how many were
+// dropped.
+func truncateFields(data map[string]interface{}, maxFields int) map[string]interface{} {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ret := make(map[string]interface{}, maxFields+1)
+	for _, k := range keys[:maxFields] {
+		ret[k] = data[k]
+	}
+	ret[truncatedFieldsField] = len(keys) - maxFields
+	return ret
+}
+
+type JSONParserFactory struct {
+	flattenDepth     int
+	separator        string
+	preserveIntegers bool
+	maxFields        int
+}
+
+func (pf *JSONParserFactory) Init(options map[string]interface{}) error {
+	var err error
+	if pf.flattenDepth, err = intOption(options, "flattenDepth"); err != nil {
+		return err
+	}
+	if pf.maxFields, err = intOption(options, "maxFields"); err != nil {
+		return err
+	}
+	if pf.flattenDepth < 0 || pf.maxFields < 0 {
+		return fmt.Errorf("flattenDepth and maxFields options must not be negative")
+	}
 
-func (pf *JSONParserFactory) Init(options map[string]interface{}) error { return nil }
+	pf.separator = "."
+	if separatorOption, ok := options["flattenSeparator"]; ok {
+		separator, ok := separatorOption.(string)
+		if !ok {
+			return fmt.Errorf("Unexpected type for flattenSeparator option (expected string, got %v)", reflect.TypeOf(separatorOption))
+		}
+		pf.separator = separator
+	}
+
+	if preserveOption, ok := options["preserveIntegers"]; ok {
+		preserve, ok := preserveOption.(bool)
+		if !ok {
+			return fmt.Errorf("Unexpected type for preserveIntegers option (expected bool, got %v)", reflect.TypeOf(preserveOption))
+		}
+		pf.preserveIntegers = preserve
+	}
+	return nil
+}
 
 func (pf *JSONParserFactory) New() Parser {
-	return &JSONParser{}
+	return &JSONParser{
+		flattenDepth:     pf.flattenDepth,
+		separator:        pf.separator,
+		preserveIntegers: pf.preserveIntegers,
+		maxFields:        pf.maxFields,
+	}
 }
@@ -0,0 +1,102 @@
+package parsers
+
+import (
+	"testing"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func newJSONParser(t *testing.T, options map[string]interface{}) Parser {
+	pf, err := NewParserFactory(&config.ParserConfig{Name: "json", Options: options})
+	assert.NoError(t, err)
+	return pf.New()
+}
+
+func TestJSONParserDefaults(t *testing.T) {
+	parser := newJSONParser(t, nil)
+	parsed, err := parser.Parse(`{"id": 1234567890123456789, "req": {"path": "/"}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"id":  float64(1234567890123456789),
+		"req": map[string]interface{}{"path": "/"},
+	}, parsed)
+}
+
+func TestJSONParserFlatten(t *testing.T) {
+	parser := newJSONParser(t, map[string]interface{}{"flattenDepth": 2})
+	parsed, err := parser.Parse(`{"a": 1, "req": {"path": "/", "tags": ["x"], "headers": {"host": "example.com", "x-ids": [1, 2]}}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"a":           float64(1),
+		"req.path":    "/",
+		"req.tags":    `["x"]`,
+		"req.headers": `{"host":"example.com","x-ids":[1,2]}`,
+	}, parsed)
+
+	parser = newJSONParser(t, map[string]interface{}{"flattenDepth": 1, "flattenSeparator": "_"})
+	parsed, err = parser.Parse(`{"tags": ["<b>"], "req": {"path": "/"}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"tags": `["<b>"]`,
+		"req":  `{"path":"/"}`,
+	}, parsed)
+
+	parser = newJSONParser(t, map[string]interface{}{"flattenDepth": 3, "flattenSeparator": "_"})
+	parsed, err = parser.Parse(`{"req": {"headers": {"host": "example.com"}}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"req_headers_host": "example.com"}, parsed)
+}
+
+func TestJSONParserPreserveIntegers(t *testing.T) {
+	parser := newJSONParser(t, map[string]interface{}{"preserveIntegers": true})
+	parsed, err := parser.Parse(`{"id": 1234567890123456789, "ratio": 0.5, "big": 1e400, "nested": {"ids": [9007199254740993]}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"id":     int64(1234567890123456789),
+		"ratio":  0.5,
+		"big":    "1e400",
+		"nested": map[string]interface{}{"ids": []interface{}{int64(9007199254740993)}},
+	}, parsed)
+
+	_, err = parser.Parse(`{"id": 1} trailing`)
+	assert.Error(t, err)
+	_, err = parser.Parse(`{"a":1}}`)
+	assert.Error(t, err)
+	_, err = parser.Parse(`{"a":1}]`)
+	assert.Error(t, err)
+	_, err = parser.Parse(`{"a":1} {"b":2}`)
+	assert.Error(t, err)
+	_, err = parser.Parse("{\"a\":1}\n")
+	assert.NoError(t, err)
+	_, err = parser.Parse(`not json`)
+	assert.Error(t, err)
+}
+
+func TestJSONParserMaxFields(t *testing.T) {
+	parser := newJSONParser(t, map[string]interface{}{"maxFields": 2, "flattenDepth": 2})
+	parsed, err := parser.Parse(`{"c": 3, "a": 1, "b": {"x": 2, "y": 4}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"a":                  float64(1),
+		"b.x":                float64(2),
+		truncatedFieldsField: 2,
+	}, parsed)
+
+	parsed, err = parser.Parse(`{"a": 1}`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": float64(1)}, parsed)
+}
+
+func TestJSONParserInvalidOptions(t *testing.T) {
+	for _, options := range []map[string]interface{}{
+		{"flattenDepth": "2"},
+		{"flattenDepth": 1.5},
+		{"maxFields": -1},
+		{"preserveIntegers": "yes"},
+		{"flattenSeparator": 1},
+	} {
+		_, err := NewParserFactory(&config.ParserConfig{Name: "json", Options: options})
+		assert.Error(t, err, "options %v", options)
+	}
+}
@@ -0,0 +1,186 @@
+package parsers
+
+// Parses Kubernetes API server audit events, as written by the log backend in
+// JSON, one event per line:
+// {"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"...",
+//  "stage":"ResponseComplete","verb":"get","user":{"username":"admin"},...}
+// https://kubernetes.io/docs/tasks/debug/debug-cluster/audit/
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// Objects whose fields are sent as e.g. user.username and objectRef.resource
+var k8sAuditNestedFields = map[string]bool{
+	"user":             true,
+	"impersonatedUser": true,
+	"objectRef":        true,
+	"responseStatus":   true,
+	"annotations":      true,
+}
+
+// The stages after which an event is complete
+var k8sAuditFinalStages = map[string]bool{
+	"ResponseComplete": true,
+	"Panic":            true,
+}
+
+type K8sAuditParserFactory struct {
+	mergeStages bool
+	cacheSize   int
+}
+
+func (pf *K8sAuditParserFactory) Init(options map[string]interface{}) error {
+	if mergeOption, ok := options["mergeStages"]; ok {
+		merge, ok := mergeOption.(bool)
+		if !ok {
+			return fmt.Errorf("Unexpected type for mergeStages option (expected bool, got %v)", reflect.TypeOf(mergeOption))
+		}
+		pf.mergeStages = merge
+	}
+
+	size, err := intOption(options, "cacheSize")
+	if err != nil {
+		return err
+	}
+	if size < 0 {
+		return fmt.Errorf("cacheSize option must not be negative")
+	}
+	if size == 0 {
+		size = cacheSize
+	}
+	pf.cacheSize = size
+	return nil
+}
+
+func (pf *K8sAuditParserFactory) New() Parser {
+	p := &K8sAuditParser{json: &JSONParser{preserveIntegers: true}}
+	if pf.mergeStages {
+		p.cache, _ = lru.New(pf.cacheSize)
+	}
+	return p
+}
+
+// K8sAuditParser is stateful when stages are merged: like AuditParser, it
+// holds the earlier stages of each request in an LRU cache keyed by audit ID,
+// and emits one event when the request completes.
+type K8sAuditParser struct {
+	json  *JSONParser
+	cache *lru.Cache
+}
+
+func (p *K8sAuditParser) Stateful() bool { return p.cache != nil }
+
+func (p *K8sAuditParser) Parse(line string) (map[string]interface{}, error) {
+	raw, err := p.json.Parse(line)
+	if err != nil {
+		return nil, err
+	}
+	id, ok := raw["auditID"].(string)
+	if !ok {
+		return nil, fmt.Errorf("Couldn't parse line as Kubernetes audit event: %s", line)
+	}
+
+	data := make(map[string]interface{}, len(raw))
+	for k, v := range raw {
+		switch {
+		case k == "kind" || k == "apiVersion":
+			// The same for every event
+		case k8sAuditNestedFields[k]:
+			if obj, ok := v.(map[string]interface{}); ok {
+				for subKey, subValue := range obj {
+					if value := k8sAuditValue(subValue); value != nil {
+						data[k+"."+subKey] = value
+					}
+				}
+			}
+		case k == "requestReceivedTimestamp" || k == "stageTimestamp" || k == "timestamp":
+			data[k] = parseK8sAuditTimestamp(v)
+		default:
+			if value := k8sAuditValue(v); value != nil {
+				data[k] = value
+			}
+		}
+	}
+	setK8sAuditDuration(data)
+
+	if p.cache == nil {
+		return data, nil
+	}
+
+	stage, _ := data["stage"].(string)
+	if cached, ok := p.cache.Peek(id); ok {
+		if prior, ok := cached.(map[string]interface{}); ok {
+			for k, v := range data {
+				prior[k] = v
+			}
+			prior["stages"] = fmt.Sprintf("%s,%s", prior["stages"], stage)
+			data = prior
+		}
+	} else {
+		data["stages"] = stage
+	}
+	if k8sAuditFinalStages[stage] {
+		p.cache.Remove(id)
+		return data, nil
+	}
+	p.cache.Add(id, data)
+	return nil, nil
+}
+
+// k8sAuditValue returns the value to send for v: lists of strings (such as
+// groups and source IPs) are joined with commas, and other structures are
+// sent as JSON. Empty structures are dropped.
+func k8sAuditValue(v interface{}) interface{} {
+	switch typed := v.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		if len(typed) == 0 {
+			return nil
+		}
+		return stringifyJSON(typed)
+	case []interface{}:
+		if len(typed) == 0 {
+			return nil
+		}
+		values := make([]string, len(typed))
+		for i, item := range typed {
+			s, ok := item.(string)
+			if !ok {
+				return stringifyJSON(typed)
+			}
+			values[i] = s
+		}
+		return strings.Join(values, ",")
+	}
+	return v
+}
+
+func parseK8sAuditTimestamp(v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		if ts, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return ts
+		}
+	}
+	return v
+}
+
+// setK8sAuditDuration sets the time from the request being received to the
+// event's stage, in milliseconds.
+func setK8sAuditDuration(data map[string]interface{}) {
+	received, ok := data["requestReceivedTimestamp"].(time.Time)
+	if !ok {
+		return
+	}
+	stageTime, ok := data["stageTimestamp"].(time.Time)
+	if !ok {
+		return
+	}
+	data["duration_ms"] = float64(stageTime.Sub(received)) / float64(time.Millisecond)
+}
@@ -0,0 +1,92 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+const k8sAuditReceived = `{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"4f6b2c1e","stage":"RequestReceived","requestURI":"/api/v1/namespaces/default/pods/web-0","verb":"get","user":{"username":"admin","groups":["system:masters","system:authenticated"]},"sourceIPs":["10.0.0.1"],"userAgent":"kubectl/v1.29.0","objectRef":{"resource":"pods","namespace":"default","name":"web-0","apiVersion":"v1"},"requestReceivedTimestamp":"2024-03-01T12:00:00.000000Z","stageTimestamp":"2024-03-01T12:00:00.000000Z"}`
+
+const k8sAuditComplete = `{"kind":"Event","apiVersion":"audit.k8s.io/v1","level":"Metadata","auditID":"4f6b2c1e","stage":"ResponseComplete","requestURI":"/api/v1/namespaces/default/pods/web-0","verb":"get","user":{"username":"admin","groups":["system:masters","system:authenticated"]},"sourceIPs":["10.0.0.1"],"userAgent":"kubectl/v1.29.0","objectRef":{"resource":"pods","namespace":"default","name":"web-0","apiVersion":"v1"},"responseStatus":{"metadata":{},"status":"Failure","reason":"NotFound","code":404},"requestReceivedTimestamp":"2024-03-01T12:00:00.000000Z","stageTimestamp":"2024-03-01T12:00:00.012500Z","annotations":{"authorization.k8s.io/decision":"allow","authorization.k8s.io/reason":""}}`
+
+func TestK8sAuditParser(t *testing.T) {
+	pf, err := NewParserFactory(&config.ParserConfig{Name: "k8s-audit"})
+	assert.NoError(t, err)
+	parser := pf.New()
+
+	parsed, err := parser.Parse(k8sAuditComplete)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"level":                    "Metadata",
+		"auditID":                  "4f6b2c1e",
+		"stage":                    "ResponseComplete",
+		"requestURI":               "/api/v1/namespaces/default/pods/web-0",
+		"verb":                     "get",
+		"user.username":            "admin",
+		"user.groups":              "system:masters,system:authenticated",
+		"sourceIPs":                "10.0.0.1",
+		"userAgent":                "kubectl/v1.29.0",
+		"objectRef.resource":       "pods",
+		"objectRef.namespace":      "default",
+		"objectRef.name":           "web-0",
+		"objectRef.apiVersion":     "v1",
+		"responseStatus.status":    "Failure",
+		"responseStatus.reason":    "NotFound",
+		"responseStatus.code":      int64(404),
+		"requestReceivedTimestamp": time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		"stageTimestamp":           time.Date(2024, 3, 1, 12, 0, 0, 12500000, time.UTC),
+		"duration_ms":              12.5,
+		"annotations.authorization.k8s.io/decision": "allow",
+		"annotations.authorization.k8s.io/reason":   "",
+	}, parsed)
+
+	// Without merging, every stage is sent
+	parsed, err = parser.Parse(k8sAuditReceived)
+	assert.NoError(t, err)
+	assert.Equal(t, "RequestReceived", parsed["stage"])
+	assert.Equal(t, 0.0, parsed["duration_ms"])
+
+	_, err = parser.Parse(`{"kind":"Pod"}`)
+	assert.Error(t, err)
+	_, err = parser.Parse(`AUDIT: id="4f6b2c1e"`)
+	assert.Error(t, err)
+}
+
+func TestK8sAuditParserMergeStages(t *testing.T) {
+	pf, err := NewParserFactory(&config.ParserConfig{
+		Name:    "k8s-audit",
+		Options: map[string]interface{}{"mergeStages": true, "cacheSize": 16},
+	})
+	assert.NoError(t, err)
+	parser := pf.New()
+
+	parsed, err := parser.Parse(k8sAuditReceived)
+	assert.NoError(t, err)
+	assert.Nil(t, parsed)
+
+	parsed, err = parser.Parse(k8sAuditComplete)
+	assert.NoError(t, err)
+	assert.Equal(t, "RequestReceived,ResponseComplete", parsed["stages"])
+	assert.Equal(t, "ResponseComplete", parsed["stage"])
+	assert.Equal(t, int64(404), parsed["responseStatus.code"])
+	assert.Equal(t, 12.5, parsed["duration_ms"])
+
+	// A request whose earlier stages weren't seen is still sent
+	parsed, err = parser.Parse(k8sAuditComplete)
+	assert.NoError(t, err)
+	assert.Equal(t, "ResponseComplete", parsed["stages"])
+}
+
+func TestK8sAuditParserInvalidOptions(t *testing.T) {
+	for _, options := range []map[string]interface{}{
+		{"mergeStages": "yes"},
+		{"cacheSize": -1},
+		{"cacheSize": "big"},
+	} {
+		_, err := NewParserFactory(&config.ParserConfig{Name: "k8s-audit", Options: options})
+		assert.Error(t, err, "options %v", options)
+	}
+}
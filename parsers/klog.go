@@ -0,0 +1,295 @@
+package parsers
+
+// klog v2 is the structured logging library used by modern Kubernetes
+// components. It writes either the glog header followed by a quoted message
+// and key/value pairs:
+// I0720 00:23:31.949027       5 controller.go:61] "Pod status updated" pod="kube-system/dns" ready=true
+// or, with `--logging-format=json`, one JSON object per line:
+// {"ts":1580306777.04728,"caller":"cmd/main.go:41","msg":"Pod status updated","v":0,"pod":"kube-system/dns"}
+// See https://github.com/kubernetes/community/blob/master/contributors/devel/sig-instrumentation/migration-to-structured-logging.md
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type KlogParserFactory struct{}
+
+func (pf *KlogParserFactory) Init(options map[string]interface{}) error { return nil }
+
+func (pf *KlogParserFactory) New() Parser {
+	return &KlogParser{
+		re: &extRegexp{regexp.MustCompile(lineformat)},
+	}
+}
+
+// KlogParser is stateful: klog writes a string value that contains line breaks
+// as `key=<`, followed by each line of the value indented with a tab, and then
+// a line starting with ` >` (which may carry further key/value pairs). The
+// parser holds on to the partial event until that closing line.
+type KlogParser struct {
+	re *extRegexp
+
+	pending      map[string]interface{}
+	pendingKey   string
+	pendingLines []string
+	// Events that are complete but haven't been returned yet, because the
+	// line that completed them produced another event too
+	ready []map[string]interface{}
+}
+
+func (p *KlogParser) Stateful() bool { return true }
+
+func (p *KlogParser) Holding() bool { return p.pending != nil || len(p.ready) > 0 }
+
+// Flush returns the next complete event, or else the event whose multi-line
+// value is being read, with the lines read so far.
+func (p *KlogParser) Flush() map[string]interface{} {
+	if len(p.ready) > 0 {
+		ret := p.ready[0]
+		p.ready = p.ready[1:]
+		return ret
+	}
+	if p.pending != nil {
+		return p.finishPending()
+	}
+	return nil
+}
+
+func (p *KlogParser) Parse(line string) (map[string]interface{}, error) {
+	events, err := p.parse(line)
+	p.ready = append(p.ready, events...)
+	// A line that isn't klog might still be parsed by another parser, e.g.
+	// in a fallback chain, so any events it completed wait for the next
+	// line or a flush
+	if err != nil || len(p.ready) == 0 {
+		return nil, err
+	}
+	ret := p.ready[0]
+	p.ready = p.ready[1:]
+	return ret, nil
+}
+
+// parse returns the events completed by line, in order.
+func (p *KlogParser) parse(line string) ([]map[string]interface{}, error) {
+	var events []map[string]interface{}
+	if p.pending != nil {
+		if strings.HasPrefix(line, "\t") {
+			p.pendingLines = append(p.pendingLines, line[1:])
+			return nil, nil
+		}
+		if strings.HasPrefix(line, " >") {
+			ret := p.finishPending()
+			if p.parseKeyValues(line[2:], ret) {
+				return nil, nil
+			}
+			return []map[string]interface{}{ret}, nil
+		}
+		// The multi-line value never finished, so send the event with the
+		// lines that were read, and then parse this line on its own.
+		events = append(events, p.finishPending())
+	}
+	ret, err := p.parseLine(line)
+	if ret != nil {
+		events = append(events, ret)
+	}
+	return events, err
+}
+
+func (p *KlogParser) parseLine(line string) (map[string]interface{}, error) {
+	if strings.HasPrefix(line, "{") {
+		return parseKlogJSON(line)
+	}
+
+	_, captures := p.re.FindStringSubmatchMap(line)
+	if captures == nil {
+		return nil, fmt.Errorf("Couldn't parse line as klog line: %s", line)
+	}
+
+	ret := make(map[string]interface{})
+	if level, ok := levels[captures["level"]]; ok {
+		ret["level"] = level
+	} else {
+		ret["level"] = captures["level"]
+	}
+	ret["filename"] = captures["filename"]
+	if threadid, err := strconv.Atoi(captures["threadid"]); err == nil {
+		ret["threadid"] = threadid
+	}
+	if lineno, err := strconv.Atoi(captures["lineno"]); err == nil {
+		ret["lineno"] = lineno
+	}
+	ts, err := parseGlogTimestamp(
+		captures["month"], captures["day"], captures["hour"], captures["minute"], captures["second"], captures["microsecond"])
+	if err == nil {
+		ret["klog_timestamp"] = ts
+	}
+
+	message := captures["message"]
+	if !strings.HasPrefix(message, `"`) {
+		// Unstructured output, e.g. from klog.Infof
+		ret["message"] = message
+		return ret, nil
+	}
+	msg, rest, err := readKlogQuoted(message)
+	if err != nil {
+		ret["message"] = message
+		return ret, nil
+	}
+	ret["message"] = msg
+	if p.parseKeyValues(rest, ret) {
+		return nil, nil
+	}
+	return ret, nil
+}
+
+func (p *KlogParser) finishPending() map[string]interface{} {
+	ret := p.pending
+	ret[p.pendingKey] = strings.Join(p.pendingLines, "\n")
+	p.pending = nil
+	p.pendingKey = ""
+	p.pendingLines = nil
+	return ret
+}
+
+// parseKeyValues adds the key/value pairs in s to ret. It returns true if the
+// last value continues on the following lines, in which case ret is held
+// until the value is complete.
+func (p *KlogParser) parseKeyValues(s string, ret map[string]interface{}) bool {
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return false
+		}
+		eq := strings.IndexByte(s, '=')
+		if eq <= 0 || strings.IndexByte(s[:eq], ' ') != -1 {
+			// Not a key/value pair; there's nothing sensible to do with
+			// the rest of the line.
+			return false
+		}
+		key := s[:eq]
+		s = s[eq+1:]
+
+		if s == "<" {
+			p.pending = ret
+			p.pendingKey = key
+			return true
+		}
+
+		// Header fields take precedence over key/value pairs
+		_, exists := ret[key]
+		var value interface{}
+		switch {
+		case strings.HasPrefix(s, `"`):
+			str, rest, err := readKlogQuoted(s)
+			if err != nil {
+				value, s = s, ""
+			} else {
+				value, s = str, rest
+			}
+		case strings.HasPrefix(s, "{"), strings.HasPrefix(s, "["):
+			// Structs and slices are formatted with %+v, and may contain
+			// spaces.
+			end := klogBracketEnd(s)
+			value, s = s[:end], s[end:]
+		default:
+			end := strings.IndexByte(s, ' ')
+			if end == -1 {
+				end = len(s)
+			}
+			value, s = typeKlogValue(s[:end]), s[end:]
+		}
+		if !exists {
+			ret[key] = value
+		}
+	}
+}
+
+// readKlogQuoted reads a Go-quoted string from the start of s, and returns its
+// unquoted value along with the rest of s.
+func readKlogQuoted(s string) (string, string, error) {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			value, err := strconv.Unquote(s[:i+1])
+			return value, s[i+1:], err
+		}
+	}
+	return "", s, fmt.Errorf("unterminated quoted string")
+}
+
+// klogBracketEnd returns the index just past the bracket that closes the one
+// at the start of s, or len(s) if there isn't one.
+func klogBracketEnd(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+func typeKlogValue(s string) interface{} {
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	// klog writes booleans as true and false; strconv.ParseBool would also
+	// turn e.g. replicas=1 into a boolean.
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return s
+}
+
+func parseKlogJSON(line string) (map[string]interface{}, error) {
+	data := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(line), &data); err != nil {
+		return nil, err
+	}
+
+	if msg, ok := data["msg"]; ok {
+		data["message"] = msg
+		delete(data, "msg")
+	}
+	if ts, ok := data["ts"].(float64); ok {
+		sec := int64(ts)
+		data["klog_timestamp"] = time.Unix(sec, int64((ts-float64(sec))*1e9)).UTC()
+		delete(data, "ts")
+	}
+	if caller, ok := data["caller"].(string); ok {
+		if idx := strings.LastIndexByte(caller, ':'); idx != -1 {
+			if lineno, err := strconv.Atoi(caller[idx+1:]); err == nil {
+				data["filename"] = caller[:idx]
+				data["lineno"] = lineno
+				delete(data, "caller")
+			}
+		}
+	}
+	// Info messages carry a verbosity; error messages don't.
+	if v, ok := data["v"].(float64); ok {
+		data["v"] = int(v)
+		data["level"] = "info"
+	} else {
+		data["level"] = "error"
+	}
+	return data, nil
+}
@@ -0,0 +1,169 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKlogParser(t *testing.T) {
+	pf := &KlogParserFactory{}
+	assert.NoError(t, pf.Init(nil))
+	parser := pf.New()
+	year := time.Now().Year()
+
+	tc := []struct {
+		lines    []string
+		expected map[string]interface{}
+	}{
+		{
+			lines: []string{`I0720 00:23:31.949027       5 controller.go:61] "Pod status updated" pod="kube-system/dns" ready=true restarts=3 ratio=0.5 err="dial tcp: \"timeout\""`},
+			expected: map[string]interface{}{
+				"level":          "info",
+				"threadid":       5,
+				"filename":       "controller.go",
+				"lineno":         61,
+				"message":        "Pod status updated",
+				"klog_timestamp": time.Date(year, 7, 20, 0, 23, 31, 949027000, time.UTC),
+				"pod":            "kube-system/dns",
+				"ready":          true,
+				"restarts":       int64(3),
+				"ratio":          0.5,
+				"err":            `dial tcp: "timeout"`,
+			},
+		},
+		{
+			// 1 and 0 are numbers, not booleans
+			lines: []string{`I0720 00:23:31.949027       5 scale.go:42] "Scaled deployment" replicas=1 ready=0 paused=false`},
+			expected: map[string]interface{}{
+				"level":          "info",
+				"threadid":       5,
+				"filename":       "scale.go",
+				"lineno":         42,
+				"message":        "Scaled deployment",
+				"klog_timestamp": time.Date(year, 7, 20, 0, 23, 31, 949027000, time.UTC),
+				"replicas":       int64(1),
+				"ready":          int64(0),
+				"paused":         false,
+			},
+		},
+		{
+			// Structs are formatted with %+v
+			lines: []string{`E0720 00:23:31.949027       5 sync.go:10] "Sync failed" obj={Name:dns Namespace:kube-system} attempt=2`},
+			expected: map[string]interface{}{
+				"level":          "error",
+				"threadid":       5,
+				"filename":       "sync.go",
+				"lineno":         10,
+				"message":        "Sync failed",
+				"klog_timestamp": time.Date(year, 7, 20, 0, 23, 31, 949027000, time.UTC),
+				"obj":            "{Name:dns Namespace:kube-system}",
+				"attempt":        int64(2),
+			},
+		},
+		{
+			// Unstructured output is passed through as the message
+			lines: []string{`W0720 00:23:31.949027       5 trace.go:61] Trace took 1.5s`},
+			expected: map[string]interface{}{
+				"level":          "warning",
+				"threadid":       5,
+				"filename":       "trace.go",
+				"lineno":         61,
+				"message":        "Trace took 1.5s",
+				"klog_timestamp": time.Date(year, 7, 20, 0, 23, 31, 949027000, time.UTC),
+			},
+		},
+		{
+			lines: []string{
+				`I1025 00:15:15.525108       1 example.go:79] "Config loaded" config=<`,
+				"\tline one",
+				"\tline two",
+				` > reloaded=true`,
+			},
+			expected: map[string]interface{}{
+				"level":          "info",
+				"threadid":       1,
+				"filename":       "example.go",
+				"lineno":         79,
+				"message":        "Config loaded",
+				"klog_timestamp": time.Date(year, 10, 25, 0, 15, 15, 525108000, time.UTC),
+				"config":         "line one\nline two",
+				"reloaded":       true,
+			},
+		},
+		{
+			lines: []string{`{"ts":1580306777.5,"caller":"cmd/main.go:41","msg":"Pod status updated","v":2,"pod":{"name":"dns","namespace":"kube-system"}}`},
+			expected: map[string]interface{}{
+				"level":          "info",
+				"v":              2,
+				"filename":       "cmd/main.go",
+				"lineno":         41,
+				"message":        "Pod status updated",
+				"klog_timestamp": time.Date(2020, 1, 29, 14, 6, 17, 500000000, time.UTC),
+				"pod":            map[string]interface{}{"name": "dns", "namespace": "kube-system"},
+			},
+		},
+		{
+			lines: []string{`{"ts":1580306777,"caller":"cmd/main.go:41","msg":"Sync failed","err":"timeout"}`},
+			expected: map[string]interface{}{
+				"level":          "error",
+				"filename":       "cmd/main.go",
+				"lineno":         41,
+				"message":        "Sync failed",
+				"klog_timestamp": time.Date(2020, 1, 29, 14, 6, 17, 0, time.UTC),
+				"err":            "timeout",
+			},
+		},
+	}
+
+	for _, tt := range tc {
+		var parsed map[string]interface{}
+		var err error
+		for i, line := range tt.lines {
+			parsed, err = parser.Parse(line)
+			assert.NoError(t, err)
+			if i < len(tt.lines)-1 {
+				assert.Nil(t, parsed)
+			}
+		}
+		assert.Equal(t, tt.expected, parsed)
+	}
+
+	_, err := parser.Parse("not a klog line")
+	assert.Error(t, err)
+}
+
+func TestKlogParserUnterminatedValue(t *testing.T) {
+	pf := &KlogParserFactory{}
+	assert.NoError(t, pf.Init(nil))
+	parser := pf.New().(*KlogParser)
+
+	var messages []interface{}
+	for _, line := range []string{
+		`I1025 00:15:15.525108       1 example.go:79] "Config loaded" config=<`,
+		"\tline one",
+		`I1025 00:15:16.000000       1 example.go:80] "Next" attempt=1`,
+		`I1025 00:15:17.000000       1 example.go:81] "Dump" data=<`,
+		"\tpartial",
+	} {
+		parsed, err := parser.Parse(line)
+		assert.NoError(t, err)
+		if parsed != nil {
+			messages = append(messages, parsed["message"])
+			if parsed["message"] == "Config loaded" {
+				// Sent with the lines read before the value was cut off
+				assert.Equal(t, "line one", parsed["config"])
+			}
+		}
+	}
+	assert.True(t, parser.Holding())
+	for parsed := parser.Flush(); parsed != nil; parsed = parser.Flush() {
+		messages = append(messages, parsed["message"])
+		if parsed["message"] == "Dump" {
+			assert.Equal(t, "partial", parsed["data"])
+		}
+	}
+	assert.False(t, parser.Holding())
+	assert.Equal(t, []interface{}{"Config loaded", "Next", "Dump"}, messages)
+}
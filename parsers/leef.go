@@ -0,0 +1,103 @@
+package parsers
+
+// Parses IBM Log Event Extended Format (LEEF) records, e.g.
+// LEEF:1.0|Microsoft|MSExchange|4.0 SP1|15345|src=192.0.2.0	dst=172.50.123.1	sev=5
+// LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.0.1.8^dst=10.0.0.5^sev=5
+// optionally preceded by a syslog header.
+// https://www.ibm.com/docs/en/dsm?topic=overview-leef-event-components
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var leefHeaderFields = []string{"leef_version", "device_vendor", "device_product", "device_version", "event_id"}
+
+// Predefined attributes whose values are integers
+var leefIntFields = map[string]bool{
+	"sev": true, "srcPort": true, "dstPort": true,
+	"srcPreNATPort": true, "dstPreNATPort": true, "srcPostNATPort": true, "dstPostNATPort": true,
+	"srcBytes": true, "dstBytes": true, "totalPackets": true, "srcPackets": true, "dstPackets": true,
+	"vSrc": true,
+}
+
+type LEEFParserFactory struct{}
+
+func (pf *LEEFParserFactory) Init(options map[string]interface{}) error { return nil }
+
+func (pf *LEEFParserFactory) New() Parser {
+	return &LEEFParser{}
+}
+
+type LEEFParser struct{}
+
+func (p *LEEFParser) Parse(line string) (map[string]interface{}, error) {
+	start := strings.Index(line, "LEEF:")
+	if start == -1 {
+		return nil, fmt.Errorf("Couldn't parse line as LEEF record: %s", line)
+	}
+	header, attributes, ok := splitSecurityHeader(line[start+len("LEEF:"):], len(leefHeaderFields))
+	if !ok {
+		return nil, fmt.Errorf("Couldn't parse LEEF header: %s", line)
+	}
+
+	ret := make(map[string]interface{})
+	if prefix := strings.TrimSpace(line[:start]); prefix != "" {
+		ret["syslog_header"] = prefix
+	}
+	for i, name := range leefHeaderFields {
+		ret[name] = header[i]
+	}
+
+	// LEEF 1.0 separates attributes with tabs; 2.0 adds an optional header
+	// field naming the separator, as a character or in hex, e.g. x5E or 0x5E
+	// for ^. Without it, the attributes follow the event ID directly, and the
+	// separator is still a tab.
+	delimiter := "\t"
+	if !strings.HasPrefix(header[0], "1") {
+		i := strings.IndexByte(attributes, '|')
+		if i != -1 && !strings.Contains(attributes[:i], "=") {
+			if spec := attributes[:i]; spec != "" {
+				d, err := parseLEEFDelimiter(spec)
+				if err != nil {
+					return nil, err
+				}
+				delimiter = d
+			}
+			attributes = attributes[i+1:]
+		}
+	}
+
+	for _, attribute := range strings.Split(attributes, delimiter) {
+		key, value, ok := strings.Cut(attribute, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			continue
+		}
+		if leefIntFields[key] {
+			if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+				ret[key] = i
+				continue
+			}
+		}
+		ret[key] = value
+	}
+	return ret, nil
+}
+
+func parseLEEFDelimiter(spec string) (string, error) {
+	if utf8.RuneCountInString(spec) == 1 {
+		return spec, nil
+	}
+	lower := strings.ToLower(spec)
+	for _, prefix := range []string{"0x", "x"} {
+		if hex, ok := strings.CutPrefix(lower, prefix); ok {
+			if n, err := strconv.ParseUint(hex, 16, 32); err == nil {
+				return string(rune(n)), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("Invalid LEEF delimiter %s", spec)
+}
@@ -0,0 +1,60 @@
+package parsers
+
+import (
+	"testing"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLEEFParser(t *testing.T) {
+	pf, err := NewParserFactory(&config.ParserConfig{Name: "leef"})
+	assert.NoError(t, err)
+	parser := pf.New()
+
+	parsed, err := parser.Parse("LEEF:1.0|Microsoft|MSExchange|4.0 SP1|15345|src=192.0.2.0\tdst=172.50.123.1\tsev=5\tcat=anomaly\tmsg=a=b c")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"leef_version":   "1.0",
+		"device_vendor":  "Microsoft",
+		"device_product": "MSExchange",
+		"device_version": "4.0 SP1",
+		"event_id":       "15345",
+		"src":            "192.0.2.0",
+		"dst":            "172.50.123.1",
+		"sev":            int64(5),
+		"cat":            "anomaly",
+		"msg":            "a=b c",
+	}, parsed)
+
+	parsed, err = parser.Parse("<13>Mar  1 12:00:00 sw01 LEEF:2.0|Lancope|StealthWatch|1.0|41|^|src=10.0.1.8^dstPort=443^usrName=joe")
+	assert.NoError(t, err)
+	assert.Equal(t, "<13>Mar  1 12:00:00 sw01", parsed["syslog_header"])
+	assert.Equal(t, "10.0.1.8", parsed["src"])
+	assert.Equal(t, int64(443), parsed["dstPort"])
+	assert.Equal(t, "joe", parsed["usrName"])
+
+	parsed, err = parser.Parse("LEEF:2.0|Vendor|Product|1.0|42|x7C|src=10.0.1.8|dst=10.0.0.5")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.5", parsed["dst"])
+
+	parsed, err = parser.Parse("LEEF:2.0|Vendor|Product|1.0|42||src=10.0.1.8\tdst=10.0.0.5")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.0.5", parsed["dst"])
+
+	// The delimiter field is optional, and defaults to a tab
+	parsed, err = parser.Parse("LEEF:2.0|Vendor|Product|1.0|42|src=10.0.1.8\tdst=10.0.0.5\tmsg=a|b")
+	assert.NoError(t, err)
+	assert.Equal(t, "10.0.1.8", parsed["src"])
+	assert.Equal(t, "10.0.0.5", parsed["dst"])
+	assert.Equal(t, "a|b", parsed["msg"])
+	assert.Equal(t, "42", parsed["event_id"])
+
+	for _, line := range []string{
+		`CEF:0|Vendor|Product|2|login|Login failed|3|`,
+		`LEEF:2.0|Vendor|Product|1.0|42|xZZ|src=10.0.1.8`,
+	} {
+		_, err = parser.Parse(line)
+		assert.Error(t, err, line)
+	}
+}
@@ -0,0 +1,176 @@
+package parsers
+
+// Parses the MySQL (and MariaDB/Percona) slow query log, which records each
+// query as a block of lines:
+// # Time: 2024-03-01T12:00:00.123456Z
+// # User@Host: app[app] @ web-1 [10.0.0.5]  Id:    42
+// # Query_time: 1.234567  Lock_time: 0.000123 Rows_sent: 10  Rows_examined: 5000
+// SET timestamp=1709294400;
+// SELECT * FROM orders WHERE customer_id = 5;
+// https://dev.mysql.com/doc/refman/8.0/en/slow-query-log.html
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	mysqlTime     = regexp.MustCompile(`^# Time: (.*)$`)
+	mysqlUserHost = regexp.MustCompile(`^# User@Host: (?P<user>[^\[]*)\[(?P<effective_user>[^\]]*)\] @ (?P<host>\S*) \[(?P<client_ip>[^\]]*)\](?:\s+Id:\s+(?P<thread_id>\d+))?`)
+	mysqlComment  = regexp.MustCompile(`([A-Za-z_]+): (\S+)`)
+	mysqlSetTime  = regexp.MustCompile(`^SET timestamp=([0-9]+);$`)
+	mysqlUse      = regexp.MustCompile(`^use ([^;]+);$`)
+	// Commands such as Quit are logged in place of a query, e.g.
+	// `# administrator command: Quit;`
+	mysqlAdminCommand = regexp.MustCompile(`^# (administrator command: .*)$`)
+	// Written when the server starts or the log is flushed
+	mysqlPreamble = regexp.MustCompile(`^(?:\S+, Version: .* started with:|Tcp port: .*|Time\s+Id\s+Command\s+Argument)$`)
+)
+
+type MySQLSlowParserFactory struct{}
+
+func (pf *MySQLSlowParserFactory) Init(options map[string]interface{}) error { return nil }
+
+func (pf *MySQLSlowParserFactory) New() Parser {
+	return &MySQLSlowParser{}
+}
+
+// MySQLSlowParser is stateful, like AuditParser: it collects the lines of each
+// entry and emits a single event once the query is complete.
+type MySQLSlowParser struct {
+	pending    map[string]interface{}
+	queryLines []string
+}
+
+func (p *MySQLSlowParser) Stateful() bool { return true }
+
+func (p *MySQLSlowParser) Parse(line string) (map[string]interface{}, error) {
+	if mysqlPreamble.MatchString(line) {
+		return nil, nil
+	}
+
+	if m := mysqlAdminCommand.FindStringSubmatch(line); m != nil {
+		// If the previous query wasn't terminated, it's sent now and the
+		// command is sent with the next entry's first line.
+		var ret map[string]interface{}
+		if len(p.queryLines) > 0 {
+			ret = p.finish()
+		}
+		if p.pending == nil {
+			p.pending = make(map[string]interface{})
+		}
+		p.queryLines = []string{m[1]}
+		if ret != nil {
+			return ret, nil
+		}
+		return p.finish(), nil
+	}
+
+	if strings.HasPrefix(line, "# ") {
+		// A header line after the query means a new entry has started,
+		// even if the previous query wasn't terminated.
+		var ret map[string]interface{}
+		if len(p.queryLines) > 0 {
+			ret = p.finish()
+		}
+		if p.pending == nil {
+			p.pending = make(map[string]interface{})
+		}
+		p.parseHeader(line)
+		return ret, nil
+	}
+
+	if p.pending == nil {
+		return nil, fmt.Errorf("Couldn't parse line as part of a MySQL slow log entry: %s", line)
+	}
+
+	if len(p.queryLines) == 0 {
+		if m := mysqlSetTime.FindStringSubmatch(line); m != nil {
+			// Only whole seconds, so `# Time:` is preferred when the entry
+			// has one
+			if _, ok := p.pending["timestamp"]; !ok {
+				if epoch, err := strconv.ParseInt(m[1], 10, 64); err == nil {
+					p.pending["timestamp"] = time.Unix(epoch, 0).UTC()
+				}
+			}
+			return nil, nil
+		}
+		if m := mysqlUse.FindStringSubmatch(line); m != nil {
+			p.pending["database"] = m[1]
+			return nil, nil
+		}
+	}
+
+	p.queryLines = append(p.queryLines, line)
+	if strings.HasSuffix(strings.TrimSpace(line), ";") {
+		return p.finish(), nil
+	}
+	return nil, nil
+}
+
+func (p *MySQLSlowParser) parseHeader(line string) {
+	if m := mysqlTime.FindStringSubmatch(line); m != nil {
+		if ts, ok := parseMySQLTime(m[1]); ok {
+			p.pending["timestamp"] = ts
+		}
+		return
+	}
+	if m := mysqlUserHost.FindStringSubmatch(line); m != nil {
+		for i, name := range mysqlUserHost.SubexpNames() {
+			if i == 0 || m[i] == "" {
+				continue
+			}
+			if name == "thread_id" {
+				if id, err := strconv.Atoi(m[i]); err == nil {
+					p.pending[name] = id
+					continue
+				}
+			}
+			p.pending[name] = strings.TrimSpace(m[i])
+		}
+		return
+	}
+	// Everything else is a list of `Key: value` pairs, e.g. Query_time,
+	// Rows_examined, or MariaDB's Schema and QC_hit.
+	for _, m := range mysqlComment.FindAllStringSubmatch(line, -1) {
+		key := strings.ToLower(m[1])
+		if key == "schema" {
+			key = "database"
+		}
+		p.pending[key] = typeMySQLValue(m[2])
+	}
+}
+
+func (p *MySQLSlowParser) finish() map[string]interface{} {
+	ret := p.pending
+	query := strings.Join(p.queryLines, "\n")
+	ret["query"] = query
+	ret["normalized_query"] = normalizeQuery(strings.TrimSuffix(strings.TrimSpace(query), ";"), true)
+	p.pending = nil
+	p.queryLines = nil
+	return ret
+}
+
+func parseMySQLTime(v string) (time.Time, bool) {
+	// MySQL 5.7+ writes RFC3339 timestamps; older versions write e.g.
+	// `240301 12:00:00`, padding single-digit hours with a space.
+	for _, layout := range []string{time.RFC3339Nano, "060102 15:04:05", "060102  15:04:05"} {
+		if ts, err := time.Parse(layout, v); err == nil {
+			return ts, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func typeMySQLValue(v string) interface{} {
+	if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return i
+	}
+	if f, err := strconv.ParseFloat(v, 64); err == nil {
+		return f
+	}
+	return v
+}
@@ -0,0 +1,85 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMySQLSlowParser(t *testing.T) {
+	pf := &MySQLSlowParserFactory{}
+	assert.NoError(t, pf.Init(nil))
+	parser := pf.New()
+
+	lines := []string{
+		"/usr/sbin/mysqld, Version: 8.0.36 (MySQL Community Server - GPL). started with:",
+		"Tcp port: 3306  Unix socket: /var/run/mysqld/mysqld.sock",
+		"Time                 Id Command    Argument",
+		"# Time: 2024-03-01T12:00:00.123456Z",
+		"# User@Host: app[app] @ web-1 [10.0.0.5]  Id:    42",
+		"# Query_time: 1.234567  Lock_time: 0.000123 Rows_sent: 10  Rows_examined: 5000",
+		"use shop;",
+		"SET timestamp=1709294400;",
+		"SELECT * FROM orders",
+		"WHERE customer_id = 5 AND status = 'open';",
+		"# User@Host: report[report] @  [10.0.0.6]  Id:    43",
+		"# Query_time: 0.5  Lock_time: 0.0 Rows_sent: 1  Rows_examined: 100",
+		"SET timestamp=1709294401;",
+		"SELECT COUNT(*) FROM orders WHERE id IN (1, 2, 3);",
+		"# User@Host: app[app] @ web-1 [10.0.0.5]  Id:    42",
+		"# Query_time: 0.000012  Lock_time: 0.000000 Rows_sent: 0  Rows_examined: 0",
+		"SET timestamp=1709294402;",
+		"# administrator command: Quit;",
+		"# User@Host: app[app] @ web-1 [10.0.0.5]  Id:    44",
+		"# Query_time: 0.1  Lock_time: 0.0 Rows_sent: 1  Rows_examined: 1",
+		"SET timestamp=1709294403;",
+		"SELECT 1;",
+	}
+	var events []map[string]interface{}
+	for _, line := range lines {
+		parsed, err := parser.Parse(line)
+		assert.NoError(t, err)
+		if parsed != nil {
+			events = append(events, parsed)
+		}
+	}
+
+	assert.Equal(t, 4, len(events))
+	assert.Equal(t, map[string]interface{}{
+		"timestamp":        time.Date(2024, 3, 1, 12, 0, 0, 123456000, time.UTC),
+		"user":             "app",
+		"effective_user":   "app",
+		"host":             "web-1",
+		"client_ip":        "10.0.0.5",
+		"thread_id":        42,
+		"query_time":       1.234567,
+		"lock_time":        0.000123,
+		"rows_sent":        int64(10),
+		"rows_examined":    int64(5000),
+		"database":         "shop",
+		"query":            "SELECT * FROM orders\nWHERE customer_id = 5 AND status = 'open';",
+		"normalized_query": "SELECT * FROM orders WHERE customer_id = ? AND status = ?",
+	}, events[0])
+	assert.Equal(t, map[string]interface{}{
+		"timestamp":        time.Date(2024, 3, 1, 12, 0, 1, 0, time.UTC),
+		"user":             "report",
+		"effective_user":   "report",
+		"client_ip":        "10.0.0.6",
+		"thread_id":        43,
+		"query_time":       0.5,
+		"lock_time":        0.0,
+		"rows_sent":        int64(1),
+		"rows_examined":    int64(100),
+		"query":            "SELECT COUNT(*) FROM orders WHERE id IN (1, 2, 3);",
+		"normalized_query": "SELECT COUNT(*) FROM orders WHERE id IN (?+)",
+	}, events[1])
+	assert.Equal(t, time.Date(2024, 3, 1, 12, 0, 2, 0, time.UTC), events[2]["timestamp"])
+	assert.Equal(t, "administrator command: Quit;", events[2]["query"])
+	assert.Equal(t, 42, events[2]["thread_id"])
+	assert.Equal(t, 44, events[3]["thread_id"])
+	assert.Equal(t, "SELECT 1;", events[3]["query"])
+
+	_, err := pf.New().Parse("SELECT 1;")
+	assert.Error(t, err)
+}
@@ -2,6 +2,8 @@ package parsers
 
 import (
 	"fmt"
+	"reflect"
+	"strconv"
 
 	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
 )
@@ -10,6 +12,31 @@ type Parser interface {
 	Parse(line string) (map[string]interface{}, error)
 }
 
+// StatefulParser is implemented by parsers that can carry state from one line
+// to the next, such as parsers that join multi-line records. These need a
+// parser per file, and can't be shared between unrelated lines.
+type StatefulParser interface {
+	Parser
+	Stateful() bool
+}
+
+// IsStateful returns whether p carries state between lines.
+func IsStateful(p Parser) bool {
+	sp, ok := p.(StatefulParser)
+	return ok && sp.Stateful()
+}
+
+// FlushableParser is implemented by parsers that hold each record back until
+// the line after it, to collect continuation lines such as stack traces.
+// Holding reports whether there's such a record, and Flush returns it, so
+// that it can be sent once the file has gone quiet instead of waiting for
+// another line. Flush is called until it returns nil.
+type FlushableParser interface {
+	Parser
+	Holding() bool
+	Flush() map[string]interface{}
+}
+
 type ParserFactory interface {
 	Init(options map[string]interface{}) error
 	New() Parser
@@ -28,16 +55,46 @@ func NewParserFactory(config *config.ParserConfig) (ParserFactory, error) {
 			// configuration
 			parserName: config.Name,
 		}
+	case "envoy-json", "istio":
+		factory = &EnvoyJSONParserFactory{}
+	case "haproxy":
+		factory = &HAProxyParserFactory{}
+	case "traefik":
+		factory = &TraefikParserFactory{}
+	case "apache":
+		factory = &ApacheParserFactory{}
+	case "csv":
+		factory = &CSVParserFactory{}
 	case "glog":
 		factory = &GlogParserFactory{}
+	case "klog":
+		factory = &KlogParserFactory{}
+	case "java":
+		factory = &JavaParserFactory{}
 	case "redis":
 		factory = &RedisParserFactory{}
 	case "keyval":
 		factory = &KeyvalParserFactory{}
+	case "postgresql":
+		factory = &PostgreSQLParserFactory{}
+	case "mysql_slow":
+		factory = &MySQLSlowParserFactory{}
 	case "audit":
 		factory = &AuditParserFactory{}
+	case "k8s-audit":
+		factory = &K8sAuditParserFactory{}
+	case "cef":
+		factory = &CEFParserFactory{}
+	case "leef":
+		factory = &LEEFParserFactory{}
 	case "regex":
 		factory = &RegexFactory{}
+	case "grok":
+		factory = &GrokParserFactory{}
+	case "auto":
+		factory = &AutoParserFactory{}
+	case "fallback":
+		factory = &FallbackParserFactory{configs: config.Parsers}
 	default:
 		return nil, fmt.Errorf("Unknown parser type %s", config.Name)
 	}
@@ -47,3 +104,68 @@ func NewParserFactory(config *config.ParserConfig) (ParserFactory, error) {
 	}
 	return factory, nil
 }
+
+// intOption reads an integer option, which is 0 if it isn't set. Numbers in
+// configuration written as JSON are decoded as floats, so those are accepted
+// too as long as they're whole.
+func intOption(options map[string]interface{}, name string) (int, error) {
+	option, ok := options[name]
+	if !ok {
+		return 0, nil
+	}
+	switch typed := option.(type) {
+	case int:
+		return typed, nil
+	case float64:
+		if typed == float64(int(typed)) {
+			return int(typed), nil
+		}
+	}
+	return 0, fmt.Errorf("Unexpected type for %s option (expected integer, got %v)", name, reflect.TypeOf(option))
+}
+
+// stringMapOption reads an option mapping names to strings, such as a parser's
+// types, which is nil if it isn't set. YAML decodes maps as
+// map[interface{}]interface{} and JSON as map[string]interface{}, so either
+// is accepted.
+func stringMapOption(options map[string]interface{}, name string) (map[string]string, error) {
+	option, ok := options[name]
+	if !ok {
+		return nil, nil
+	}
+	value := reflect.ValueOf(option)
+	if value.Kind() != reflect.Map {
+		return nil, fmt.Errorf("Unexpected type for %s option (expected map, got %v)", name, reflect.TypeOf(option))
+	}
+	ret := make(map[string]string, value.Len())
+	for _, key := range value.MapKeys() {
+		k, keyOk := key.Interface().(string)
+		v, valueOk := value.MapIndex(key).Interface().(string)
+		if !keyOk || !valueOk {
+			return nil, fmt.Errorf("Unexpected type for %s option (expected %v to map to a string)", name, key.Interface())
+		}
+		ret[k] = v
+	}
+	return ret, nil
+}
+
+// convertValue converts a captured string to the int, float or bool type named
+// by valueType. Values of any other type, or that don't parse, are left as
+// strings.
+func convertValue(value string, valueType string) interface{} {
+	switch valueType {
+	case "int":
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case "float":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "bool":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
@@ -0,0 +1,241 @@
+package parsers
+
+// Parses the stderr log output of a PostgreSQL server, e.g.
+// 2024-03-01 12:00:00.123 UTC [42] LOG:  duration: 0.412 ms  statement: SELECT 1
+// The prefix before the severity is set by log_line_prefix:
+// https://www.postgresql.org/docs/current/runtime-config-logging.html
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PostgreSQL's default log_line_prefix
+const defaultPostgresPrefix = "%m [%p] "
+
+// Matches an escape in log_line_prefix, e.g. %m or %-10u
+var postgresEscape = regexp.MustCompile(`%(-?[0-9]*)([a-zA-Z%])`)
+
+var postgresEscapes = map[string]struct {
+	field   string
+	pattern string
+}{
+	"a": {"application_name", `.*?`},
+	"u": {"user", `.*?`},
+	"d": {"database", `.*?`},
+	"r": {"remote_host", `\S*?`},
+	"h": {"remote_host", `\S*?`},
+	"b": {"backend_type", `.*?`},
+	"p": {"pid", `\d+`},
+	"P": {"leader_pid", `\d*`},
+	"t": {"timestamp", `\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?: [A-Za-z0-9:+-]+)?`},
+	"m": {"timestamp", `\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}\.\d+(?: [A-Za-z0-9:+-]+)?`},
+	"n": {"timestamp", `\d+\.\d+`},
+	"i": {"command_tag", `.*?`},
+	"e": {"sql_state", `[0-9A-Z]{5}`},
+	"c": {"session_id", `[0-9a-f]+\.[0-9a-f]+`},
+	"l": {"session_line", `\d+`},
+	"s": {"session_start", `\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}(?: [A-Za-z0-9:+-]+)?`},
+	"v": {"virtual_transaction_id", `\S*?`},
+	"x": {"transaction_id", `\d+`},
+	"Q": {"query_id", `-?\d+`},
+}
+
+// Lines with these severities add detail to the message before them, rather
+// than starting a new one.
+var postgresSecondaryFields = map[string]string{
+	"DETAIL":    "detail",
+	"HINT":      "hint",
+	"QUERY":     "internal_query",
+	"CONTEXT":   "context",
+	"LOCATION":  "location",
+	"STATEMENT": "statement",
+}
+
+// Matches `duration: 1.234 ms  statement: SELECT 1`, as logged by
+// log_min_duration_statement. Extended query protocol messages are logged as
+// e.g. `execute <unnamed>: SELECT 1`.
+var postgresDuration = regexp.MustCompile(`(?s)^duration: ([0-9.]+) ms(?:\s+(?:statement|(?:parse|bind|execute)[^:]*): (.*))?$`)
+var postgresStatement = regexp.MustCompile(`(?s)^(?:statement|(?:parse|bind|execute)[^:]*): (.*)$`)
+
+type PostgreSQLParserFactory struct {
+	re *extRegexp
+}
+
+func (pf *PostgreSQLParserFactory) Init(options map[string]interface{}) error {
+	prefix := defaultPostgresPrefix
+	if prefixOption, ok := options["prefix"]; ok {
+		typedPrefixOption, ok := prefixOption.(string)
+		if !ok {
+			return fmt.Errorf("Unexpected type for prefix option (expected string, got %v)", reflect.TypeOf(prefixOption))
+		}
+		prefix = typedPrefixOption
+	}
+
+	expr, err := postgresPrefixToRegexp(prefix)
+	if err != nil {
+		return err
+	}
+	re, err := newExtRegexp("^" + expr + `(?P<level>[A-Z0-9]+):\s+(?P<message>.*)$`)
+	if err != nil {
+		return fmt.Errorf("Invalid PostgreSQL log prefix `%s`: %v", prefix, err)
+	}
+	pf.re = re
+	return nil
+}
+
+func (pf *PostgreSQLParserFactory) New() Parser {
+	return &PostgreSQLParser{re: pf.re}
+}
+
+// postgresPrefixToRegexp translates a log_line_prefix setting into a regular
+// expression with a named group for each escape.
+func postgresPrefixToRegexp(prefix string) (string, error) {
+	var expr strings.Builder
+	optional := false
+	prev := 0
+	for _, loc := range postgresEscape.FindAllStringSubmatchIndex(prefix, -1) {
+		expr.WriteString(regexp.QuoteMeta(prefix[prev:loc[0]]))
+		prev = loc[1]
+		padding := prefix[loc[2]:loc[3]]
+		name := prefix[loc[4]:loc[5]]
+		switch name {
+		case "%":
+			expr.WriteString("%")
+		case "q":
+			// Non-session processes stop writing the prefix here
+			expr.WriteString("(?:")
+			optional = true
+		default:
+			escape, ok := postgresEscapes[name]
+			if !ok {
+				return "", fmt.Errorf("Unsupported PostgreSQL log_line_prefix escape %s", prefix[loc[0]:loc[1]])
+			}
+			group := `(?P<` + escape.field + `>` + escape.pattern + `)`
+			switch {
+			case strings.HasPrefix(padding, "-"):
+				group += " *"
+			case padding != "":
+				group = " *" + group
+			}
+			expr.WriteString(group)
+		}
+	}
+	expr.WriteString(regexp.QuoteMeta(prefix[prev:]))
+	if optional {
+		expr.WriteString(")?")
+	}
+	return expr.String(), nil
+}
+
+// PostgreSQLParser is stateful: a message can be followed by continuation
+// lines (e.g. the rest of a multi-line statement) and by DETAIL, HINT,
+// STATEMENT etc. lines, so each event is held until the next message starts.
+type PostgreSQLParser struct {
+	re *extRegexp
+
+	pending   map[string]interface{}
+	lastField string
+}
+
+func (p *PostgreSQLParser) Stateful() bool { return true }
+
+func (p *PostgreSQLParser) Holding() bool { return p.pending != nil }
+
+func (p *PostgreSQLParser) Flush() map[string]interface{} {
+	prior := p.pending
+	p.pending = nil
+	if prior == nil {
+		return nil
+	}
+	return finishPostgresEvent(prior)
+}
+
+func (p *PostgreSQLParser) Parse(line string) (map[string]interface{}, error) {
+	_, captures := p.re.FindStringSubmatchMap(line)
+	if captures == nil {
+		if p.pending == nil {
+			return nil, fmt.Errorf("Couldn't parse line as PostgreSQL log line: %s", line)
+		}
+		// A continuation of the previous line
+		prior, _ := p.pending[p.lastField].(string)
+		p.pending[p.lastField] = prior + "\n" + strings.TrimPrefix(line, "\t")
+		return nil, nil
+	}
+
+	if field, ok := postgresSecondaryFields[captures["level"]]; ok && p.pending != nil &&
+		(captures["pid"] == "" || fmt.Sprint(p.pending["pid"]) == captures["pid"]) {
+		p.pending[field] = captures["message"]
+		p.lastField = field
+		return nil, nil
+	}
+
+	ret := make(map[string]interface{}, len(captures))
+	for k, v := range captures {
+		if v == "" {
+			continue
+		}
+		switch k {
+		case "level":
+			ret[k] = strings.ToLower(v)
+		case "pid", "leader_pid", "session_line", "transaction_id":
+			if i, err := strconv.Atoi(v); err == nil {
+				ret[k] = i
+			} else {
+				ret[k] = v
+			}
+		case "timestamp", "session_start":
+			ret[k] = parsePostgresTimestamp(v)
+		default:
+			ret[k] = v
+		}
+	}
+
+	prior := p.pending
+	p.pending = ret
+	p.lastField = "message"
+	if prior == nil {
+		return nil, nil
+	}
+	return finishPostgresEvent(prior), nil
+}
+
+func parsePostgresTimestamp(v string) interface{} {
+	if epoch, err := strconv.ParseFloat(v, 64); err == nil {
+		sec := int64(epoch)
+		return time.Unix(sec, int64((epoch-float64(sec))*1e9)).UTC()
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05.999999999 MST", "2006-01-02 15:04:05 MST", "2006-01-02 15:04:05.999999999 -07", "2006-01-02 15:04:05 -07"} {
+		if ts, err := time.Parse(layout, v); err == nil {
+			return ts
+		}
+	}
+	return v
+}
+
+// finishPostgresEvent pulls the duration and query out of a complete message.
+func finishPostgresEvent(ev map[string]interface{}) map[string]interface{} {
+	message, _ := ev["message"].(string)
+	var query string
+	if m := postgresDuration.FindStringSubmatch(message); m != nil {
+		if duration, err := strconv.ParseFloat(m[1], 64); err == nil {
+			ev["duration_ms"] = duration
+		}
+		query = m[2]
+	} else if m := postgresStatement.FindStringSubmatch(message); m != nil {
+		query = m[1]
+	}
+	if query != "" {
+		ev["query"] = query
+	} else if statement, ok := ev["statement"].(string); ok {
+		query = statement
+	}
+	if query != "" {
+		ev["normalized_query"] = normalizeQuery(query, false)
+	}
+	return ev
+}
@@ -0,0 +1,123 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgreSQLParser(t *testing.T) {
+	pf, err := NewParserFactory(&config.ParserConfig{Name: "postgresql"})
+	assert.NoError(t, err)
+	parser := pf.New()
+
+	lines := []string{
+		`2024-03-01 12:00:00.123 UTC [42] LOG:  duration: 0.412 ms  statement: SELECT *`,
+		"\tFROM users",
+		"\tWHERE id = 17 AND name = 'O''Brien'",
+		`2024-03-01 12:00:01.000 UTC [43] ERROR:  relation "nope" does not exist at character 15`,
+		`2024-03-01 12:00:01.000 UTC [43] STATEMENT:  SELECT * FROM nope WHERE id IN (1, 2, 3)`,
+		`2024-03-01 12:00:02.500 UTC [44] LOG:  checkpoint starting: time`,
+	}
+	var events []map[string]interface{}
+	for _, line := range lines {
+		parsed, err := parser.Parse(line)
+		assert.NoError(t, err)
+		if parsed != nil {
+			events = append(events, parsed)
+		}
+	}
+
+	// The last message is held until the next one starts
+	assert.Equal(t, 2, len(events))
+	assert.Equal(t, map[string]interface{}{
+		"timestamp":        time.Date(2024, 3, 1, 12, 0, 0, 123000000, time.UTC),
+		"pid":              42,
+		"level":            "log",
+		"message":          "duration: 0.412 ms  statement: SELECT *\nFROM users\nWHERE id = 17 AND name = 'O''Brien'",
+		"duration_ms":      0.412,
+		"query":            "SELECT *\nFROM users\nWHERE id = 17 AND name = 'O''Brien'",
+		"normalized_query": "SELECT * FROM users WHERE id = ? AND name = ?",
+	}, events[0])
+	assert.Equal(t, map[string]interface{}{
+		"timestamp":        time.Date(2024, 3, 1, 12, 0, 1, 0, time.UTC),
+		"pid":              43,
+		"level":            "error",
+		"message":          `relation "nope" does not exist at character 15`,
+		"statement":        "SELECT * FROM nope WHERE id IN (1, 2, 3)",
+		"normalized_query": "SELECT * FROM nope WHERE id IN (?+)",
+	}, events[1])
+
+	_, err = pf.New().Parse("not a postgres line")
+	assert.Error(t, err)
+}
+
+func TestPostgreSQLParserCustomPrefix(t *testing.T) {
+	pf, err := NewParserFactory(&config.ParserConfig{
+		Name:    "postgresql",
+		Options: map[string]interface{}{"prefix": "%t [%p]: user=%u,db=%d,app=%a,client=%h "},
+	})
+	assert.NoError(t, err)
+	parser := pf.New()
+
+	parsed, err := parser.Parse(`2024-03-01 12:00:00 UTC [7]: user=api,db=shop,app=psql,client=10.0.0.5 LOG:  execute S_1: UPDATE carts SET total = $1 WHERE id = 99`)
+	assert.NoError(t, err)
+	assert.Nil(t, parsed)
+	parsed, err = parser.Parse(`2024-03-01 12:00:01 UTC [8]: user=,db=,app=,client= LOG:  checkpoint starting: time`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"timestamp":        time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		"pid":              7,
+		"user":             "api",
+		"database":         "shop",
+		"application_name": "psql",
+		"remote_host":      "10.0.0.5",
+		"level":            "log",
+		"message":          "execute S_1: UPDATE carts SET total = $1 WHERE id = 99",
+		"query":            "UPDATE carts SET total = $1 WHERE id = 99",
+		"normalized_query": "UPDATE carts SET total = $1 WHERE id = ?",
+	}, parsed)
+
+	_, err = NewParserFactory(&config.ParserConfig{
+		Name:    "postgresql",
+		Options: map[string]interface{}{"prefix": "%Z "},
+	})
+	assert.Error(t, err)
+}
+
+func TestPostgreSQLParserFlush(t *testing.T) {
+	pf, err := NewParserFactory(&config.ParserConfig{Name: "postgresql"})
+	assert.NoError(t, err)
+	parser := pf.New().(FlushableParser)
+
+	assert.Nil(t, parser.Flush())
+	data, err := parser.Parse(`2024-03-01 12:00:01.000 UTC [43] ERROR:  relation "nope" does not exist at character 15`)
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+	data, err = parser.Parse(`2024-03-01 12:00:01.000 UTC [43] STATEMENT:  SELECT * FROM nope`)
+	assert.NoError(t, err)
+	assert.Nil(t, data)
+
+	data = parser.Flush()
+	assert.Equal(t, 43, data["pid"])
+	assert.Equal(t, "SELECT * FROM nope", data["statement"])
+	assert.Nil(t, parser.Flush())
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	tc := []struct {
+		query               string
+		doubleQuotedStrings bool
+		expected            string
+	}{
+		{"SELECT * FROM t1 WHERE a = 1.5 AND b = 'x'", false, "SELECT * FROM t1 WHERE a = ? AND b = ?"},
+		{`SELECT "Col" FROM t WHERE c = $tag$it's$tag$`, false, `SELECT "Col" FROM t WHERE c = ?`},
+		{`SELECT * FROM t WHERE c = "x" AND d IN (1,2,  3)`, true, "SELECT * FROM t WHERE c = ? AND d IN (?+)"},
+		{"INSERT INTO `t` VALUES (1, 'a\\'b')", true, "INSERT INTO `t` VALUES (?+)"},
+	}
+	for _, tt := range tc {
+		assert.Equal(t, tt.expected, normalizeQuery(tt.query, tt.doubleQuotedStrings))
+	}
+}
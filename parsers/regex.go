@@ -2,11 +2,42 @@ package parsers
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
+	"strings"
+	"time"
 )
 
+// A capture group name can end in a type suffix, e.g. `(?P<status__int>\d+)`,
+// to convert its value from a string.
+const regexTypeSeparator = "__"
+
+var regexTypes = map[string]bool{"string": true, "int": true, "float": true, "bool": true, "time": true}
+
+// Layouts tried for time captures when no timeFormat option is given
+var regexTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05.999999999",
+	"02/Jan/2006:15:04:05 -0700",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+type regexField struct {
+	name      string
+	fieldType string
+}
+
+type regexExpression struct {
+	re *regexp.Regexp
+	// Indexed by capture group; unnamed groups have an empty name.
+	fields []regexField
+}
+
 type RegexFactory struct {
-	expressions []string
+	expressions []*regexExpression
+	timeFormat  string
 }
 
 func (rf *RegexFactory) Init(options map[string]interface{}) error {
@@ -19,41 +50,99 @@ func (rf *RegexFactory) Init(options map[string]interface{}) error {
 		return fmt.Errorf("regex parser missing patterns option")
 	}
 
-	rf.expressions = make([]string, len(expressions))
+	types, err := stringMapOption(options, "types")
+	if err != nil {
+		return err
+	}
+	for field, fieldType := range types {
+		if !regexTypes[fieldType] {
+			return fmt.Errorf("Unknown type %s for field %s (expected int, float, bool, time or string)", fieldType, field)
+		}
+	}
+
+	if timeFormatOption, ok := options["timeFormat"]; ok {
+		timeFormat, ok := timeFormatOption.(string)
+		if !ok {
+			return fmt.Errorf("Unexpected type for timeFormat option (expected string, got %v)", reflect.TypeOf(timeFormatOption))
+		}
+		rf.timeFormat = timeFormat
+	}
+
+	rf.expressions = make([]*regexExpression, len(expressions))
 	for i, s := range expressions {
 		expression, ok := s.(string)
 		if !ok {
 			return fmt.Errorf("expected expression %s to be string", s)
 		}
-		rf.expressions[i] = expression
+		re, err := regexp.Compile(expression)
+		if err != nil {
+			return fmt.Errorf("Invalid regex expression `%s`: %v", expression, err)
+		}
+		rf.expressions[i] = newRegexExpression(re, types)
 	}
 	return nil
 }
 
-func (rf *RegexFactory) New() Parser {
-	re := make([]*extRegexp, len(rf.expressions))
-	for i, p := range rf.expressions {
-		re[i] = &extRegexp{regexp.MustCompile(p)}
+func newRegexExpression(re *regexp.Regexp, types map[string]string) *regexExpression {
+	names := re.SubexpNames()
+	expr := &regexExpression{re: re, fields: make([]regexField, len(names))}
+	for i, name := range names {
+		field := regexField{name: name}
+		if idx := strings.LastIndex(name, regexTypeSeparator); idx > 0 && regexTypes[name[idx+len(regexTypeSeparator):]] {
+			field.name = name[:idx]
+			field.fieldType = name[idx+len(regexTypeSeparator):]
+		}
+		if fieldType, ok := types[field.name]; ok {
+			field.fieldType = fieldType
+		}
+		expr.fields[i] = field
 	}
-	return &RegexParser{re: re}
+	return expr
+}
+
+func (rf *RegexFactory) New() Parser {
+	return &RegexParser{expressions: rf.expressions, timeFormat: rf.timeFormat}
 }
 
 type RegexParser struct {
-	re []*extRegexp
+	expressions []*regexExpression
+	timeFormat  string
 }
 
 func (rp *RegexParser) Parse(line string) (map[string]interface{}, error) {
-	var captures map[string]interface{}
-
-	for _, re := range rp.re {
-		_, captures = re.FindStringSubmatchIfaceMap(line)
-		if captures != nil {
-			break
+	for _, expr := range rp.expressions {
+		match := expr.re.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		captures := make(map[string]interface{})
+		for i, field := range expr.fields {
+			// ignore the full match and unnamed groups
+			if i == 0 || field.name == "" {
+				continue
+			}
+			captures[field.name] = rp.convert(match[i], field.fieldType)
 		}
+		return captures, nil
 	}
+	return nil, fmt.Errorf("Couldn't parse line with any supplied regexes: %s", line)
+}
 
-	if captures == nil {
-		return nil, fmt.Errorf("Couldn't parse line with any supplied regexes: %s", line)
+// convert returns value as fieldType, or unchanged if it can't be converted.
+func (rp *RegexParser) convert(value string, fieldType string) interface{} {
+	if fieldType != "time" {
+		return convertValue(value, fieldType)
+	}
+	if rp.timeFormat != "" {
+		if ts, err := time.Parse(rp.timeFormat, value); err == nil {
+			return ts
+		}
+		return value
+	}
+	for _, layout := range regexTimeLayouts {
+		if ts, err := time.Parse(layout, value); err == nil {
+			return ts
+		}
 	}
-	return captures, nil
+	return value
 }
@@ -2,6 +2,7 @@ package parsers
 
 import (
 	"testing"
+	"time"
 
 	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
 	"github.com/stretchr/testify/assert"
@@ -43,3 +44,71 @@ func TestRegexParser(t *testing.T) {
 		}
 	}
 }
+
+func TestRegexParserTypedCaptures(t *testing.T) {
+	cfg := &config.ParserConfig{
+		Name: "regex", Options: map[string]interface{}{
+			"expressions": []interface{}{
+				`^(?P<time__time>\S+) (?P<status__int>\d+) (?P<duration>\S+) (?P<cached__bool>\w+) (?P<path__other>\S+)$`,
+			},
+			"types": map[interface{}]interface{}{
+				"duration": "float",
+			},
+		},
+	}
+	pf, err := NewParserFactory(cfg)
+	assert.NoError(t, err)
+	parser := pf.New()
+
+	parsed, err := parser.Parse("2024-03-01T12:00:00Z 200 0.25 true /")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"time":        time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		"status":      int64(200),
+		"duration":    0.25,
+		"cached":      true,
+		"path__other": "/",
+	}, parsed)
+
+	// Values that can't be converted are left as strings
+	parsed, err = parser.Parse("yesterday 200 slow maybe /")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"time":        "yesterday",
+		"status":      int64(200),
+		"duration":    "slow",
+		"cached":      "maybe",
+		"path__other": "/",
+	}, parsed)
+}
+
+func TestRegexParserTimeFormat(t *testing.T) {
+	cfg := &config.ParserConfig{
+		Name: "regex", Options: map[string]interface{}{
+			"expressions": []interface{}{`^(?P<ts>\w{3} [ \d]\d \S+ \d{4}) (?P<msg>.*)$`},
+			"types":       map[string]interface{}{"ts": "time"},
+			"timeFormat":  "Jan _2 15:04:05 2006",
+		},
+	}
+	pf, err := NewParserFactory(cfg)
+	assert.NoError(t, err)
+
+	parsed, err := pf.New().Parse("Mar  1 12:00:00 2024 started")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"ts":  time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		"msg": "started",
+	}, parsed)
+}
+
+func TestRegexParserInvalidOptions(t *testing.T) {
+	for _, options := range []map[string]interface{}{
+		{"expressions": []interface{}{`(?P<broken`}},
+		{"expressions": []interface{}{`(?P<a>.*)`}, "types": map[string]interface{}{"a": "duration"}},
+		{"expressions": []interface{}{`(?P<a>.*)`}, "types": []interface{}{"a"}},
+		{"expressions": []interface{}{`(?P<a>.*)`}, "timeFormat": 1},
+	} {
+		_, err := NewParserFactory(&config.ParserConfig{Name: "regex", Options: options})
+		assert.Error(t, err, "options %v", options)
+	}
+}
@@ -0,0 +1,113 @@
+package parsers
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Matches a parenthesized list of placeholders, as left behind by an IN list
+// or VALUES row once its literals have been replaced.
+var placeholderList = regexp.MustCompile(`\(\?(?:\s*,\s*\?)+\)`)
+
+// normalizeQuery replaces the literal values in a SQL statement with `?`, so
+// that queries which differ only in their parameters share the same shape.
+// Whitespace is collapsed, and lists of placeholders are collapsed to `(?+)`.
+// If doubleQuotedStrings is true, double-quoted text is treated as a string
+// literal (as in MySQL) rather than an identifier (as in PostgreSQL).
+func normalizeQuery(query string, doubleQuotedStrings bool) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	lastSpace := true
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || (c == '"' && doubleQuotedStrings):
+			i = skipQuoted(query, i, c)
+			b.WriteByte('?')
+			lastSpace = false
+			continue
+		case c == '"' || c == '`':
+			// Quoted identifier
+			end := skipQuoted(query, i, c)
+			b.WriteString(query[i:end])
+			i = end
+			lastSpace = false
+			continue
+		case c == '$' && i+1 < len(query) && (query[i+1] == '$' || isIdentStart(query[i+1])):
+			// PostgreSQL dollar-quoted string, e.g. $$text$$ or $tag$text$tag$
+			if end := skipDollarQuoted(query, i); end > i {
+				i = end
+				b.WriteByte('?')
+				lastSpace = false
+				continue
+			}
+		case c >= '0' && c <= '9' && (i == 0 || !isIdentChar(query[i-1])):
+			if i > 0 && query[i-1] == '$' {
+				// Positional parameter such as $1
+				break
+			}
+			for i < len(query) && (isIdentChar(query[i]) || query[i] == '.') {
+				i++
+			}
+			b.WriteByte('?')
+			lastSpace = false
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if !lastSpace {
+				b.WriteByte(' ')
+				lastSpace = true
+			}
+			i++
+			continue
+		}
+		b.WriteByte(c)
+		lastSpace = false
+		i++
+	}
+	normalized := strings.TrimSpace(b.String())
+	return placeholderList.ReplaceAllString(normalized, "(?+)")
+}
+
+// skipQuoted returns the index just past the quoted text starting at start.
+// The quote character can be escaped by doubling it or with a backslash.
+func skipQuoted(s string, start int, quote byte) int {
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// skipDollarQuoted returns the index just past the dollar-quoted string
+// starting at start, or start if there isn't one.
+func skipDollarQuoted(s string, start int) int {
+	tagEnd := start + 1
+	for tagEnd < len(s) && isIdentChar(s[tagEnd]) {
+		tagEnd++
+	}
+	if tagEnd >= len(s) || s[tagEnd] != '$' {
+		return start
+	}
+	tag := s[start : tagEnd+1]
+	end := strings.Index(s[tagEnd+1:], tag)
+	if end == -1 {
+		return len(s)
+	}
+	return tagEnd + 1 + end + len(tag)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || (c >= '0' && c <= '9') || c >= 0x80
+}
@@ -0,0 +1,91 @@
+package parsers
+
+// Parses Traefik access logs, in either of the formats Traefik can write them:
+// the Common Log Format with Traefik's extra fields, e.g.
+// 10.0.0.1 - - [01/Mar/2024:12:00:00 +0000] "GET / HTTP/1.1" 200 19 "-" "curl/8.0" 42 "web@docker" "http://172.17.0.3:80" 1ms
+// or JSON. See https://doc.traefik.io/traefik/observability/access-logs/
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var traefikCLFLine = regexp.MustCompile(`^(?P<remote_addr>\S+) - (?P<remote_user>\S+) \[(?P<time_local>[^\]]+)\] ` +
+	`"(?P<request>[^"]*)" (?P<status>\S+) (?P<body_bytes_sent>\S+) "(?P<http_referer>[^"]*)" "(?P<http_user_agent>[^"]*)" ` +
+	`(?P<request_count>\d+) "(?P<router_name>[^"]*)" "(?P<service_url>[^"]*)" (?P<duration_ms>\d+)ms$`)
+
+// Durations in Traefik's JSON logs are in nanoseconds; these are also sent in
+// milliseconds under the given names.
+var traefikDurations = map[string]string{
+	"Duration":       "duration_ms",
+	"OriginDuration": "origin_duration_ms",
+	"Overhead":       "overhead_ms",
+}
+
+type TraefikParserFactory struct{}
+
+func (pf *TraefikParserFactory) Init(options map[string]interface{}) error { return nil }
+
+func (pf *TraefikParserFactory) New() Parser {
+	return &TraefikParser{json: &JSONParser{preserveIntegers: true}}
+}
+
+type TraefikParser struct {
+	json *JSONParser
+}
+
+func (p *TraefikParser) Parse(line string) (map[string]interface{}, error) {
+	if strings.HasPrefix(line, "{") {
+		return p.parseJSON(line)
+	}
+
+	match := traefikCLFLine.FindStringSubmatch(line)
+	if match == nil {
+		return nil, fmt.Errorf("Couldn't parse line as Traefik access log line: %s", line)
+	}
+	fields := make(map[string]string, len(match))
+	for i, name := range traefikCLFLine.SubexpNames() {
+		if i > 0 {
+			fields[name] = match[i]
+		}
+	}
+	ret := typeifyParsedLine(fields)
+	if request, ok := ret["request"].(string); ok {
+		splitRequestLine(ret, request)
+	}
+	return ret, nil
+}
+
+func (p *TraefikParser) parseJSON(line string) (map[string]interface{}, error) {
+	data, err := p.json.Parse(line)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range data {
+		switch typed := v.(type) {
+		case nil:
+			delete(data, k)
+		case string:
+			switch {
+			case typed == "-":
+				delete(data, k)
+			case k == "ClientPort" || k == "RequestPort":
+				if port, err := strconv.Atoi(typed); err == nil {
+					data[k] = port
+				}
+			case k == "StartUTC" || k == "StartLocal":
+				if ts, err := time.Parse(time.RFC3339Nano, typed); err == nil {
+					data[k] = ts
+				}
+			}
+		case int64:
+			if name, ok := traefikDurations[k]; ok {
+				data[name] = float64(typed) / float64(time.Millisecond)
+			}
+		}
+	}
+	return data, nil
+}
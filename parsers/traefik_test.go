@@ -0,0 +1,54 @@
+package parsers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraefikParser(t *testing.T) {
+	pf, err := NewParserFactory(&config.ParserConfig{Name: "traefik"})
+	assert.NoError(t, err)
+	parser := pf.New()
+
+	parsed, err := parser.Parse(`10.0.0.1 - - [01/Mar/2024:12:00:00 +0000] "GET /api?q=1 HTTP/1.1" 200 19 "-" "curl/8.0" 42 "web@docker" "http://172.17.0.3:80" 12ms`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"remote_addr":      "10.0.0.1",
+		"time_local":       "01/Mar/2024:12:00:00 +0000",
+		"request":          "GET /api?q=1 HTTP/1.1",
+		"request_method":   "GET",
+		"request_uri":      "/api?q=1",
+		"request_protocol": "HTTP/1.1",
+		"status":           int64(200),
+		"body_bytes_sent":  int64(19),
+		"http_user_agent":  "curl/8.0",
+		"request_count":    int64(42),
+		"router_name":      "web@docker",
+		"service_url":      "http://172.17.0.3:80",
+		"duration_ms":      int64(12),
+	}, parsed)
+
+	parsed, err = parser.Parse(`{"ClientHost":"10.0.0.1","ClientPort":"54321","ClientUsername":"-","DownstreamStatus":200,"Duration":1234567,"OriginDuration":1100000,"Overhead":134567,"RequestMethod":"GET","RequestPath":"/","RequestPort":"-","RouterName":"web@docker","StartUTC":"2024-03-01T12:00:00.123456789Z","ServiceURL":null}`)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"ClientHost":         "10.0.0.1",
+		"ClientPort":         54321,
+		"DownstreamStatus":   int64(200),
+		"Duration":           int64(1234567),
+		"duration_ms":        1.234567,
+		"OriginDuration":     int64(1100000),
+		"origin_duration_ms": 1.1,
+		"Overhead":           int64(134567),
+		"overhead_ms":        0.134567,
+		"RequestMethod":      "GET",
+		"RequestPath":        "/",
+		"RouterName":         "web@docker",
+		"StartUTC":           time.Date(2024, 3, 1, 12, 0, 0, 123456789, time.UTC),
+	}, parsed)
+
+	_, err = parser.Parse(`10.0.0.1 - - [01/Mar/2024:12:00:00 +0000] "GET / HTTP/1.1" 200 19`)
+	assert.Error(t, err)
+}
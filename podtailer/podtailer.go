@@ -40,6 +40,7 @@ type PodSetTailer struct {
 	wg                     sync.WaitGroup
 	legacyLogPaths         bool
 	additionalFieldsGlobal map[string]interface{}
+	schema                 *processors.SchemaProcessor
 }
 
 func NewPodSetTailer(
@@ -50,6 +51,7 @@ func NewPodSetTailer(
 	kubeClient corev1.PodsGetter,
 	legacyLogPaths bool,
 	additionalFieldsGlobal map[string]interface{},
+	schema *processors.SchemaProcessor,
 ) *PodSetTailer {
 	return &PodSetTailer{
 		config:                 config,
@@ -60,6 +62,7 @@ func NewPodSetTailer(
 		stop:                   make(chan bool),
 		legacyLogPaths:         legacyLogPaths,
 		additionalFieldsGlobal: additionalFieldsGlobal,
+		schema:                 schema,
 	}
 }
 
@@ -409,6 +412,10 @@ func (pt *PodSetTailer) watcherForPod(pod *v1.Pod, containerName string, podWatc
 	if pt.additionalFieldsGlobal != nil {
 		additionalProcessors = append(additionalProcessors, &processors.AdditionalFieldsProcessor{AdditionalFields: pt.additionalFieldsGlobal})
 	}
+	// the schema runs last, once every other processor has set fields
+	if pt.schema != nil {
+		additionalProcessors = append(additionalProcessors, pt.schema)
+	}
 	handlerFactory, err := handlers.NewLineHandlerFactoryFromConfig(
 		pt.config,
 		&unwrappers.InferUnwrapper{},
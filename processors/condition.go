@@ -0,0 +1,296 @@
+package processors
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
+	"github.com/mitchellh/mapstructure"
+)
+
+var (
+	ErrConditionUnspecified  = errors.New("condition requires a 'field', or one of 'and', 'or' or 'not'")
+	ErrConditionNoComparison = errors.New("condition requires a comparison such as 'equals' or 'exists'")
+)
+
+// A condition tests an event's fields. Conditions are written in YAML as e.g.
+//
+//	field: status
+//	gte: 500
+//
+// where all of the comparisons given must hold, or combined with and, or and
+// not:
+//
+//	or:
+//	  - field: kind
+//	    equals: http
+//	  - not:
+//	      field: error
+//	      exists: true
+type condition struct {
+	field     string
+	exists    *bool
+	hasEquals bool
+	equals    interface{}
+	in        []interface{}
+	regex     *regexp.Regexp
+	prefix    *string
+	gt, gte   *float64
+	lt, lte   *float64
+	and, or   []*condition
+	not       *condition
+}
+
+type conditionConfig struct {
+	Field  string
+	Exists *bool
+	Equals interface{}
+	In     []interface{}
+	Regex  *string
+	Prefix *string
+	Gt     *float64
+	Gte    *float64
+	Lt     *float64
+	Lte    *float64
+	And    []interface{}
+	Or     []interface{}
+	Not    interface{}
+}
+
+func newCondition(raw interface{}) (*condition, error) {
+	config := &conditionConfig{}
+	md := &mapstructure.Metadata{}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:      config,
+		Metadata:    md,
+		ErrorUnused: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(raw); err != nil {
+		return nil, fmt.Errorf("Invalid condition: %v", err)
+	}
+
+	c := &condition{
+		field:  config.Field,
+		exists: config.Exists,
+		equals: config.Equals,
+		in:     config.In,
+		prefix: config.Prefix,
+		gt:     config.Gt,
+		gte:    config.Gte,
+		lt:     config.Lt,
+		lte:    config.Lte,
+	}
+	for _, key := range md.Keys {
+		if strings.EqualFold(key, "equals") {
+			c.hasEquals = true
+		}
+	}
+	if config.Regex != nil {
+		c.regex, err = regexp.Compile(*config.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid regex in condition: %v", err)
+		}
+	}
+	for _, sub := range config.And {
+		subCondition, err := newCondition(sub)
+		if err != nil {
+			return nil, err
+		}
+		c.and = append(c.and, subCondition)
+	}
+	for _, sub := range config.Or {
+		subCondition, err := newCondition(sub)
+		if err != nil {
+			return nil, err
+		}
+		c.or = append(c.or, subCondition)
+	}
+	if config.Not != nil {
+		c.not, err = newCondition(config.Not)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	hasComparison := c.exists != nil || c.hasEquals || c.in != nil || c.regex != nil ||
+		c.prefix != nil || c.gt != nil || c.gte != nil || c.lt != nil || c.lte != nil
+	isCombination := c.and != nil || c.or != nil || c.not != nil
+	switch {
+	case c.field == "" && !isCombination:
+		return nil, ErrConditionUnspecified
+	case c.field != "" && !hasComparison:
+		return nil, ErrConditionNoComparison
+	case c.field == "" && hasComparison:
+		return nil, ErrConditionUnspecified
+	}
+	return c, nil
+}
+
+// Matches returns whether the event satisfies every part of the condition.
+func (c *condition) Matches(ev *event.Event) bool {
+	for _, sub := range c.and {
+		if !sub.Matches(ev) {
+			return false
+		}
+	}
+	if c.or != nil {
+		matched := false
+		for _, sub := range c.or {
+			if sub.Matches(ev) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if c.not != nil && c.not.Matches(ev) {
+		return false
+	}
+	if c.field == "" {
+		return true
+	}
+
+	val, ok := ev.Data[c.field]
+	if c.exists != nil && ok != *c.exists {
+		return false
+	}
+	if !ok {
+		// Only `exists: false` can match a missing field
+		return c.exists != nil && !c.hasEquals && c.in == nil && c.regex == nil &&
+			c.prefix == nil && c.gt == nil && c.gte == nil && c.lt == nil && c.lte == nil
+	}
+
+	if c.hasEquals && !valuesEqual(val, c.equals) {
+		return false
+	}
+	if c.in != nil {
+		found := false
+		for _, candidate := range c.in {
+			if valuesEqual(val, candidate) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if c.regex != nil && !c.regex.MatchString(valueString(val)) {
+		return false
+	}
+	if c.prefix != nil && !strings.HasPrefix(valueString(val), *c.prefix) {
+		return false
+	}
+	if c.gt != nil || c.gte != nil || c.lt != nil || c.lte != nil {
+		n, ok := toFloat(val)
+		if !ok ||
+			(c.gt != nil && !(n > *c.gt)) ||
+			(c.gte != nil && !(n >= *c.gte)) ||
+			(c.lt != nil && !(n < *c.lt)) ||
+			(c.lte != nil && !(n <= *c.lte)) {
+			return false
+		}
+	}
+	return true
+}
+
+// valuesEqual compares an event value with one from configuration. Numbers
+// are compared by value, so that 200 matches both the integer 200 and the
+// string "200", and booleans likewise match "true" and "false".
+func valuesEqual(val, expected interface{}) bool {
+	switch typed := expected.(type) {
+	case bool:
+		switch v := val.(type) {
+		case bool:
+			return v == typed
+		case string:
+			b, err := strconv.ParseBool(v)
+			return err == nil && b == typed
+		}
+		return false
+	case nil:
+		return val == nil
+	}
+	if expectedNumber, ok := toFloat(expected); ok {
+		if _, isString := expected.(string); !isString {
+			n, ok := toFloat(val)
+			return ok && n == expectedNumber
+		}
+	}
+	return valueString(val) == valueString(expected)
+}
+
+func valueString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// toFloat converts numbers, and strings containing numbers, to float64.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int8:
+		return float64(n), true
+	case int16:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint:
+		return float64(n), true
+	case uint8:
+		return float64(n), true
+	case uint16:
+		return float64(n), true
+	case uint32:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float32:
+		return float64(n), true
+	case float64:
+		return n, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// conditionalProcessor runs a processor only on events that match a
+// condition, and passes other events through unchanged.
+type conditionalProcessor struct {
+	Processor
+	condition *condition
+}
+
+func (p *conditionalProcessor) Start() {
+	if bp, ok := p.Processor.(BackgroundProcessor); ok {
+		bp.Start()
+	}
+}
+
+func (p *conditionalProcessor) Stop() {
+	if bp, ok := p.Processor.(BackgroundProcessor); ok {
+		bp.Stop()
+	}
+}
+
+func (p *conditionalProcessor) Process(ev *event.Event) bool {
+	if ev.Data == nil || !p.condition.Matches(ev) {
+		return true
+	}
+	return p.Processor.Process(ev)
+}
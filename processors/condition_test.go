@@ -0,0 +1,124 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func conditionFromYAML(t *testing.T, snippet string) (*condition, error) {
+	var raw interface{}
+	assert.NoError(t, yaml.Unmarshal([]byte(snippet), &raw))
+	return newCondition(raw)
+}
+
+func TestConditionMatches(t *testing.T) {
+	data := map[string]interface{}{
+		"kind":    "http",
+		"status":  int64(503),
+		"latency": "12.5",
+		"cached":  true,
+		"path":    "/api/v1/users",
+		"retried": "false",
+	}
+
+	tc := []struct {
+		condition string
+		matches   bool
+	}{
+		{`{field: kind, equals: http}`, true},
+		{`{field: kind, equals: grpc}`, false},
+		{`{field: status, equals: 503}`, true},
+		{`{field: status, equals: "503"}`, true},
+		{`{field: latency, equals: 12.5}`, true},
+		{`{field: cached, equals: true}`, true},
+		{`{field: retried, equals: false}`, true},
+		{`{field: cached, equals: "true"}`, true},
+		{`{field: status, in: [500, 502, 503]}`, true},
+		{`{field: kind, in: [grpc, tcp]}`, false},
+		{`{field: status, exists: true}`, true},
+		{`{field: error, exists: true}`, false},
+		{`{field: error, exists: false}`, true},
+		{`{field: error, equals: ""}`, false},
+		{`{field: path, regex: "^/api/v[0-9]+/"}`, true},
+		{`{field: status, regex: "^5"}`, true},
+		{`{field: path, prefix: /api}`, true},
+		{`{field: path, prefix: /static}`, false},
+		{`{field: status, gte: 500}`, true},
+		{`{field: status, gte: 500, lt: 503}`, false},
+		{`{field: latency, gt: 10, lte: 20}`, true},
+		{`{field: kind, gt: 10}`, false},
+		{`{and: [{field: kind, equals: http}, {field: status, gte: 500}]}`, true},
+		{`{and: [{field: kind, equals: http}, {field: status, lt: 500}]}`, false},
+		{`{or: [{field: kind, equals: grpc}, {field: status, lt: 500}]}`, false},
+		{`{or: [{field: kind, equals: grpc}, {field: cached, equals: true}]}`, true},
+		{`{not: {field: status, gte: 500}}`, false},
+		{`{not: {field: error, exists: true}}`, true},
+	}
+	for _, tt := range tc {
+		c, err := conditionFromYAML(t, tt.condition)
+		assert.NoError(t, err, tt.condition)
+		if err == nil {
+			assert.Equal(t, tt.matches, c.Matches(&event.Event{Data: data}), tt.condition)
+		}
+	}
+}
+
+func TestInvalidConditions(t *testing.T) {
+	for _, snippet := range []string{
+		`{}`,
+		`{field: status}`,
+		`{equals: 200}`,
+		`{field: status, equal: 200}`,
+		`{field: path, regex: "("}`,
+		`{field: status, gt: high}`,
+		`{and: [{field: status}]}`,
+		`{not: {}}`,
+		`status`,
+	} {
+		_, err := conditionFromYAML(t, snippet)
+		assert.Error(t, err, snippet)
+	}
+}
+
+func TestConditionalProcessor(t *testing.T) {
+	p, err := NewProcessor("drop_field", map[string]interface{}{
+		"field": "body",
+		"if": map[interface{}]interface{}{
+			"field":  "kind",
+			"equals": "http",
+		},
+	})
+	assert.NoError(t, err)
+
+	ev := &event.Event{Data: map[string]interface{}{"kind": "http", "body": "..."}}
+	assert.True(t, p.Process(ev))
+	assert.NotContains(t, ev.Data, "body")
+
+	ev = &event.Event{Data: map[string]interface{}{"kind": "grpc", "body": "..."}}
+	assert.True(t, p.Process(ev))
+	assert.Contains(t, ev.Data, "body")
+
+	_, err = NewProcessor("drop_field", map[string]interface{}{
+		"field": "body",
+		"if":    map[interface{}]interface{}{"field": "kind"},
+	})
+	assert.Error(t, err)
+}
+
+func TestConditionalProcessorStartsAndStops(t *testing.T) {
+	p, err := NewProcessor("sample", map[string]interface{}{
+		"type": "dynamic",
+		"rate": 10,
+		"if":   map[interface{}]interface{}{"field": "kind", "equals": "http"},
+	})
+	assert.NoError(t, err)
+	sampler := p.(*conditionalProcessor).Processor.(*Sampler)
+
+	p.(BackgroundProcessor).Start()
+	assert.NotNil(t, sampler.stopReport)
+	p.(BackgroundProcessor).Stop()
+	assert.Nil(t, sampler.stopReport)
+}
@@ -0,0 +1,233 @@
+package processors
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
+	"github.com/mitchellh/mapstructure"
+)
+
+// The field that conversion failures are recorded in
+const schemaErrorsField = "meta.schema_errors"
+
+var (
+	ErrConvertFieldsUnspecified = errors.New("convert processor requires at least one field in 'fields'")
+)
+
+// The types fields can be converted to
+var convertTypes = map[string]bool{
+	"int": true, "float": true, "bool": true, "string": true, "duration_ms": true, "timestamp": true,
+}
+
+// Layouts tried for timestamp strings when no timeFormat is given
+var convertTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999 -0700 MST",
+	"2006-01-02 15:04:05.999999999 -0700",
+	"2006-01-02 15:04:05.999999999",
+	"02/Jan/2006:15:04:05 -0700",
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RubyDate,
+	time.UnixDate,
+}
+
+// FieldConverter converts fields to the given types, so that a field has the
+// same type in Honeycomb whichever service sent it. Values that can't be
+// converted are left as they are, and the failure is recorded in
+// meta.schema_errors.
+type FieldConverter struct {
+	config     *fieldConverterConfig
+	conversion *conversion
+}
+
+type fieldConverterConfig struct {
+	Fields     map[string]string
+	TimeFormat string
+}
+
+func (f *FieldConverter) Init(options map[string]interface{}) error {
+	config := &fieldConverterConfig{}
+	err := mapstructure.Decode(options, config)
+	if err != nil {
+		return err
+	}
+	if len(config.Fields) == 0 {
+		return ErrConvertFieldsUnspecified
+	}
+	f.conversion, err = newConversion(config.Fields, config.TimeFormat)
+	if err != nil {
+		return err
+	}
+	f.config = config
+	return nil
+}
+
+func (f *FieldConverter) Process(ev *event.Event) bool {
+	if ev.Data != nil {
+		f.conversion.apply(ev)
+	}
+	return true
+}
+
+// conversion is a set of fields and the types they should have.
+type conversion struct {
+	fields     []string
+	types      map[string]string
+	timeFormat string
+}
+
+func newConversion(types map[string]string, timeFormat string) (*conversion, error) {
+	c := &conversion{types: types, timeFormat: timeFormat}
+	for field, fieldType := range types {
+		if !convertTypes[fieldType] {
+			return nil, fmt.Errorf("Unknown type %s for field %s (expected int, float, bool, string, duration_ms or timestamp)", fieldType, field)
+		}
+		c.fields = append(c.fields, field)
+	}
+	// Convert in a consistent order, so errors are reported consistently
+	sort.Strings(c.fields)
+	return c, nil
+}
+
+func (c *conversion) apply(ev *event.Event) {
+	var errs []string
+	for _, field := range c.fields {
+		val, ok := ev.Data[field]
+		if !ok || val == nil {
+			continue
+		}
+		converted, err := convertValue(val, c.types[field], c.timeFormat)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", field, err))
+			continue
+		}
+		ev.Data[field] = converted
+	}
+	if len(errs) == 0 {
+		return
+	}
+	if existing, ok := ev.Data[schemaErrorsField].(string); ok && existing != "" {
+		errs = append([]string{existing}, errs...)
+	}
+	ev.Data[schemaErrorsField] = strings.Join(errs, "; ")
+}
+
+// convertValue returns val as the given type, or an error if it can't be
+// converted.
+func convertValue(val interface{}, fieldType, timeFormat string) (interface{}, error) {
+	switch fieldType {
+	case "int":
+		if _, isBool := val.(bool); !isBool {
+			if f, ok := toFloat(val); ok && f == math.Trunc(f) && !math.IsInf(f, 0) {
+				if s, isString := val.(string); isString {
+					// Avoid losing precision on large integers
+					if i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+						return i, nil
+					}
+				}
+				if i, isInt := val.(int64); isInt {
+					return i, nil
+				}
+				// Converting a float outside int64's range has no
+				// defined result. float64(math.MaxInt64) is 2^63, which
+				// is already out of range.
+				if f >= math.MinInt64 && f < math.MaxInt64 {
+					return int64(f), nil
+				}
+			}
+		}
+	case "float":
+		if _, isBool := val.(bool); !isBool {
+			if f, ok := toFloat(val); ok {
+				return f, nil
+			}
+		}
+	case "bool":
+		switch v := val.(type) {
+		case bool:
+			return v, nil
+		case string:
+			if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+				return b, nil
+			}
+		default:
+			if f, ok := toFloat(v); ok && (f == 0 || f == 1) {
+				return f == 1, nil
+			}
+		}
+	case "string":
+		switch v := val.(type) {
+		case string:
+			return v, nil
+		case time.Time:
+			return v.Format(time.RFC3339Nano), nil
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("can't convert %T to string", val)
+		}
+		return valueString(val), nil
+	case "duration_ms":
+		// Numbers are taken to be milliseconds already; strings can also be
+		// Go durations such as 1.5s or 250ms.
+		switch v := val.(type) {
+		case time.Duration:
+			return float64(v) / float64(time.Millisecond), nil
+		case string:
+			if d, err := time.ParseDuration(strings.TrimSpace(v)); err == nil {
+				return float64(d) / float64(time.Millisecond), nil
+			}
+		}
+		if _, isBool := val.(bool); !isBool {
+			if f, ok := toFloat(val); ok {
+				return f, nil
+			}
+		}
+	case "timestamp":
+		if ts, ok := convertTimestamp(val, timeFormat); ok {
+			return ts, nil
+		}
+	}
+	return nil, fmt.Errorf("can't convert %q to %s", valueString(val), fieldType)
+}
+
+// convertTimestamp parses a time from a string, or from a number of seconds,
+// milliseconds, microseconds or nanoseconds since the epoch.
+func convertTimestamp(val interface{}, timeFormat string) (time.Time, bool) {
+	switch v := val.(type) {
+	case time.Time:
+		return v, true
+	case bool:
+		return time.Time{}, false
+	case string:
+		s := strings.TrimSpace(v)
+		if timeFormat != "" {
+			ts, err := time.Parse(timeFormat, s)
+			return ts, err == nil
+		}
+		for _, layout := range convertTimeLayouts {
+			if ts, err := time.Parse(layout, s); err == nil {
+				return ts, true
+			}
+		}
+	}
+	f, ok := toFloat(val)
+	if !ok || f <= 0 {
+		return time.Time{}, false
+	}
+	switch {
+	case f >= 1e17:
+		return time.Unix(0, int64(f)).UTC(), true
+	case f >= 1e14:
+		return time.UnixMicro(int64(f)).UTC(), true
+	case f >= 1e11:
+		return time.UnixMilli(int64(f)).UTC(), true
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC(), true
+}
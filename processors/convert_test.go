@@ -0,0 +1,150 @@
+package processors
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertValue(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tc := []struct {
+		val       interface{}
+		fieldType string
+		expected  interface{}
+	}{
+		{"200", "int", int64(200)},
+		{float64(200), "int", int64(200)},
+		{" 9007199254740993 ", "int", int64(9007199254740993)},
+		{"9223372036854775807", "int", int64(math.MaxInt64)},
+		{float64(math.MinInt64), "int", int64(math.MinInt64)},
+		{"1.5", "float", 1.5},
+		{int64(3), "float", float64(3)},
+		{"true", "bool", true},
+		{float64(0), "bool", false},
+		{float64(200), "string", "200"},
+		{true, "string", "true"},
+		{ts, "string", "2024-03-01T12:00:00Z"},
+		{"1.5s", "duration_ms", float64(1500)},
+		{"250µs", "duration_ms", 0.25},
+		{"12.5", "duration_ms", 12.5},
+		{int64(40), "duration_ms", float64(40)},
+		{"2024-03-01T12:00:00Z", "timestamp", ts},
+		{"2024-03-01 12:00:00", "timestamp", ts},
+		{float64(1709294400), "timestamp", ts},
+		{int64(1709294400000), "timestamp", ts},
+		{"1709294400000000", "timestamp", ts},
+	}
+	for _, tt := range tc {
+		converted, err := convertValue(tt.val, tt.fieldType, "")
+		assert.NoError(t, err, "%v to %s", tt.val, tt.fieldType)
+		if ts, ok := converted.(time.Time); ok {
+			assert.True(t, ts.Equal(tt.expected.(time.Time)), "%v to %s", tt.val, tt.fieldType)
+			continue
+		}
+		assert.Equal(t, tt.expected, converted, "%v to %s", tt.val, tt.fieldType)
+	}
+
+	for _, tt := range []struct {
+		val       interface{}
+		fieldType string
+	}{
+		{"abc", "int"},
+		{"1.5", "int"},
+		{float64(1e19), "int"},
+		{float64(-1e19), "int"},
+		{"9223372036854775808", "int"},
+		{true, "int"},
+		{"fast", "float"},
+		{"maybe", "bool"},
+		{float64(2), "bool"},
+		{map[string]interface{}{}, "string"},
+		{"soon", "duration_ms"},
+		{"yesterday", "timestamp"},
+		{true, "timestamp"},
+	} {
+		_, err := convertValue(tt.val, tt.fieldType, "")
+		assert.Error(t, err, "%v to %s", tt.val, tt.fieldType)
+	}
+
+	converted, err := convertValue("01/03/2024 12:00", "timestamp", "02/01/2006 15:04")
+	assert.NoError(t, err)
+	assert.True(t, ts.Equal(converted.(time.Time)))
+}
+
+func TestConvert(t *testing.T) {
+	processor := &FieldConverter{}
+	err := processor.Init(map[string]interface{}{
+		"fields": map[interface{}]interface{}{
+			"status":   "int",
+			"duration": "duration_ms",
+			"cached":   "bool",
+		},
+	})
+	assert.NoError(t, err)
+
+	e := &event.Event{Data: map[string]interface{}{
+		"status":   "503",
+		"duration": "1.2s",
+		"cached":   "sometimes",
+		"other":    "unchanged",
+	}}
+	assert.True(t, processor.Process(e))
+	assert.Equal(t, map[string]interface{}{
+		"status":             int64(503),
+		"duration":           float64(1200),
+		"cached":             "sometimes",
+		"other":              "unchanged",
+		"meta.schema_errors": `cached: can't convert "sometimes" to bool`,
+	}, e.Data)
+
+	assert.Error(t, (&FieldConverter{}).Init(map[string]interface{}{}))
+	assert.Error(t, (&FieldConverter{}).Init(map[string]interface{}{
+		"fields": map[string]interface{}{"status": "integer"},
+	}))
+}
+
+func TestSchemaFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schema.yaml")
+	err := os.WriteFile(path, []byte(`
+web:
+  status: int
+  latency: float
+api:
+  status: string
+`), 0644)
+	assert.NoError(t, err)
+
+	schema, err := LoadSchemaFile(path)
+	assert.NoError(t, err)
+
+	e := &event.Event{Dataset: "web", Data: map[string]interface{}{
+		"status":             float64(200),
+		"latency":            "slow",
+		"meta.schema_errors": "earlier: problem",
+	}}
+	assert.True(t, schema.Process(e))
+	assert.Equal(t, map[string]interface{}{
+		"status":             int64(200),
+		"latency":            "slow",
+		"meta.schema_errors": `earlier: problem; latency: can't convert "slow" to float`,
+	}, e.Data)
+
+	e = &event.Event{Dataset: "api", Data: map[string]interface{}{"status": float64(200)}}
+	assert.True(t, schema.Process(e))
+	assert.Equal(t, map[string]interface{}{"status": "200"}, e.Data)
+
+	e = &event.Event{Dataset: "other", Data: map[string]interface{}{"status": float64(200)}}
+	assert.True(t, schema.Process(e))
+	assert.Equal(t, map[string]interface{}{"status": float64(200)}, e.Data)
+
+	err = os.WriteFile(path, []byte("web:\n  status: number\n"), 0644)
+	assert.NoError(t, err)
+	_, err = LoadSchemaFile(path)
+	assert.Error(t, err)
+}
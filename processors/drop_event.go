@@ -2,62 +2,96 @@ package processors
 
 import (
 	"errors"
-	"fmt"
+
 	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
 	"github.com/mitchellh/mapstructure"
-	"github.com/sirupsen/logrus"
 )
 
 var (
-	ErrFilterOptionUnspecified = errors.New("drop_event processor requires a 'Field' to be set")
+	ErrFilterOptionUnspecified = errors.New("drop_event and keep_event processors require a 'field' or a 'match' condition to be set")
 )
 
-type EventDropper struct {
-	config *eventDropperConfig
-	values map[string]struct{}
+// eventFilterConfig configures drop_event and keep_event. Events are filtered
+// on whether a field equals one of a list of values, on a condition (see
+// condition.go), or both.
+type eventFilterConfig struct {
+	Field  string
+	Values []interface{}
+	Match  interface{}
 }
 
-type eventDropperConfig struct {
-	Field  string
-	Values []string
+// eventFilter is the part of drop_event and keep_event that decides whether
+// an event is filtered.
+type eventFilter struct {
+	field  string
+	values *condition
+	match  *condition
 }
 
-func (f *EventDropper) Init(options map[string]interface{}) error {
-	config := &eventDropperConfig{}
+func (f *eventFilter) init(options map[string]interface{}) error {
+	config := &eventFilterConfig{}
 	err := mapstructure.Decode(options, config)
 	if err != nil {
 		return err
 	}
-
-	if config.Field == "" {
+	if config.Field == "" && config.Match == nil {
 		return ErrFilterOptionUnspecified
 	}
-	f.config = config
 
-	f.values = make(map[string]struct{}, len(f.config.Values))
-	for _, val := range f.config.Values {
-		f.values[val] = struct{}{}
+	if config.Field != "" {
+		f.field = config.Field
+		f.values = &condition{field: config.Field, in: config.Values}
+		if f.values.in == nil {
+			f.values.in = []interface{}{}
+		}
+	}
+	if config.Match != nil {
+		f.match, err = newCondition(config.Match)
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (f *EventDropper) Process(ev *event.Event) bool {
+// applies returns whether the filter has anything to say about the event.
+// Events without the configured field are left alone, to avoid accidentally
+// dropping them.
+func (f *eventFilter) applies(ev *event.Event) bool {
 	if ev.Data == nil {
-		return true
+		return false
 	}
-	val, ok := ev.Data[f.config.Field]
-	if !ok {
-		return true
+	if f.field != "" {
+		if _, ok := ev.Data[f.field]; !ok {
+			return false
+		}
 	}
-	valString, ok := val.(string)
-	if !ok {
-		logrus.WithFields(logrus.Fields{
-			"key":   f.config.Field,
-			"value": val,
-			"type":  fmt.Sprintf("%T", val)}).
-			Debug("Not filtering field of non-string type")
+	return true
+}
+
+// matches returns whether the event matches both the values list and the
+// condition, where they're configured.
+func (f *eventFilter) matches(ev *event.Event) bool {
+	if f.values != nil && !f.values.Matches(ev) {
+		return false
+	}
+	if f.match != nil && !f.match.Matches(ev) {
+		return false
+	}
+	return true
+}
+
+type EventDropper struct {
+	filter eventFilter
+}
+
+func (f *EventDropper) Init(options map[string]interface{}) error {
+	return f.filter.init(options)
+}
+
+func (f *EventDropper) Process(ev *event.Event) bool {
+	if !f.filter.applies(ev) {
 		return true
 	}
-	_, exists := f.values[valString]
-	return !exists
+	return !f.filter.matches(ev)
 }
@@ -0,0 +1,102 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventDropperValues(t *testing.T) {
+	p := &EventDropper{}
+	err := p.Init(map[string]interface{}{
+		"field":  "status",
+		"values": []interface{}{200, "304", true},
+	})
+	assert.NoError(t, err)
+
+	tc := []struct {
+		data map[string]interface{}
+		keep bool
+	}{
+		{map[string]interface{}{"status": int64(200)}, false},
+		{map[string]interface{}{"status": float64(200)}, false},
+		{map[string]interface{}{"status": "200"}, false},
+		{map[string]interface{}{"status": float64(304)}, false},
+		{map[string]interface{}{"status": true}, false},
+		{map[string]interface{}{"status": float64(500)}, true},
+		{map[string]interface{}{"status": false}, true},
+		{map[string]interface{}{"other": float64(200)}, true},
+	}
+	for _, tt := range tc {
+		assert.Equal(t, tt.keep, p.Process(&event.Event{Data: tt.data}), "%v", tt.data)
+	}
+}
+
+func TestEventDropperMatch(t *testing.T) {
+	p := &EventDropper{}
+	err := p.Init(map[string]interface{}{
+		"match": map[interface{}]interface{}{
+			"and": []interface{}{
+				map[interface{}]interface{}{"field": "user_agent", "prefix": "kube-probe/"},
+				map[interface{}]interface{}{"field": "status", "equals": 200},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.False(t, p.Process(&event.Event{Data: map[string]interface{}{
+		"user_agent": "kube-probe/1.29", "status": float64(200)}}))
+	assert.True(t, p.Process(&event.Event{Data: map[string]interface{}{
+		"user_agent": "kube-probe/1.29", "status": float64(503)}}))
+	assert.True(t, p.Process(&event.Event{Data: map[string]interface{}{
+		"user_agent": "curl/8.0", "status": float64(200)}}))
+	assert.True(t, p.Process(&event.Event{Data: map[string]interface{}{
+		"status": float64(200)}}))
+}
+
+func TestEventKeeperMatch(t *testing.T) {
+	p := &EventKeeper{}
+	err := p.Init(map[string]interface{}{
+		"match": map[interface{}]interface{}{
+			"or": []interface{}{
+				map[interface{}]interface{}{"field": "status", "gte": 500},
+				map[interface{}]interface{}{"field": "error", "exists": true},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	assert.True(t, p.Process(&event.Event{Data: map[string]interface{}{"status": float64(502)}}))
+	assert.True(t, p.Process(&event.Event{Data: map[string]interface{}{"status": float64(200), "error": "timeout"}}))
+	assert.False(t, p.Process(&event.Event{Data: map[string]interface{}{"status": float64(200)}}))
+	assert.False(t, p.Process(&event.Event{Data: map[string]interface{}{}}))
+}
+
+func TestEventKeeperFieldAndMatch(t *testing.T) {
+	p := &EventKeeper{}
+	err := p.Init(map[string]interface{}{
+		"field":  "namespace",
+		"values": []interface{}{"prod"},
+		"match":  map[interface{}]interface{}{"field": "path", "regex": "^/api/"},
+	})
+	assert.NoError(t, err)
+
+	assert.True(t, p.Process(&event.Event{Data: map[string]interface{}{"namespace": "prod", "path": "/api/users"}}))
+	assert.False(t, p.Process(&event.Event{Data: map[string]interface{}{"namespace": "prod", "path": "/healthz"}}))
+	assert.False(t, p.Process(&event.Event{Data: map[string]interface{}{"namespace": "dev", "path": "/api/users"}}))
+	// Events without the field are kept
+	assert.True(t, p.Process(&event.Event{Data: map[string]interface{}{"path": "/healthz"}}))
+}
+
+func TestInvalidEventFilters(t *testing.T) {
+	for _, options := range []map[string]interface{}{
+		{},
+		{"values": []interface{}{"a"}},
+		{"match": map[interface{}]interface{}{"field": "status"}},
+		{"match": map[interface{}]interface{}{"field": "path", "regex": "("}},
+	} {
+		assert.Error(t, (&EventDropper{}).Init(options), "%v", options)
+		assert.Error(t, (&EventKeeper{}).Init(options), "%v", options)
+	}
+}
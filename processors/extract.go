@@ -0,0 +1,123 @@
+package processors
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
+	"github.com/honeycombio/honeycomb-kubernetes-agent/parsers"
+	"github.com/mitchellh/mapstructure"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrExtractFieldUnspecified    = errors.New("extract processor requires a 'field' to be set")
+	ErrExtractPatternsUnspecified = errors.New("extract processor requires at least one pattern in 'patterns'")
+)
+
+// FieldExtractor applies regular expressions with named capture groups to a
+// field, and adds the captures to the event. Captures are typed the same way
+// as by the regex parser, e.g. `user_id=(?P<user_id__int>\d+)`.
+type FieldExtractor struct {
+	config   *fieldExtractorConfig
+	patterns []parsers.Parser
+}
+
+type fieldExtractorConfig struct {
+	Field        string
+	Patterns     []string
+	Types        interface{}
+	TimeFormat   string
+	Prefix       string
+	FirstMatch   bool
+	DeleteSource bool
+}
+
+func (f *FieldExtractor) Init(options map[string]interface{}) error {
+	config := &fieldExtractorConfig{}
+	err := mapstructure.Decode(options, config)
+	if err != nil {
+		return err
+	}
+	if config.Field == "" {
+		return ErrExtractFieldUnspecified
+	}
+	if len(config.Patterns) == 0 {
+		return ErrExtractPatternsUnspecified
+	}
+
+	// Each pattern gets its own regex parser, since the parser stops at the
+	// first expression that matches and we may want all of them.
+	f.patterns = make([]parsers.Parser, len(config.Patterns))
+	for i, pattern := range config.Patterns {
+		parserOptions := map[string]interface{}{
+			"expressions": []interface{}{pattern},
+		}
+		if config.Types != nil {
+			parserOptions["types"] = config.Types
+		}
+		if config.TimeFormat != "" {
+			parserOptions["timeFormat"] = config.TimeFormat
+		}
+		factory := &parsers.RegexFactory{}
+		if err := factory.Init(parserOptions); err != nil {
+			return fmt.Errorf("Error setting up extract processor: %v", err)
+		}
+		f.patterns[i] = factory.New()
+	}
+	f.config = config
+	return nil
+}
+
+func (f *FieldExtractor) Process(ev *event.Event) bool {
+	if ev.Data == nil {
+		return true
+	}
+	val, ok := ev.Data[f.config.Field]
+	if !ok {
+		return true
+	}
+	valString, ok := val.(string)
+	if !ok {
+		logrus.WithFields(logrus.Fields{
+			"key":   f.config.Field,
+			"value": val,
+			"type":  fmt.Sprintf("%T", val)}).
+			Debug("Not extracting from field of non-string type")
+		return true
+	}
+
+	var extracted map[string]interface{}
+	for _, pattern := range f.patterns {
+		captures, err := pattern.Parse(valString)
+		if err != nil {
+			continue
+		}
+		if extracted == nil {
+			extracted = make(map[string]interface{}, len(captures))
+		}
+		for k, v := range captures {
+			// Skip optional groups that didn't participate in the match
+			if s, ok := v.(string); ok && s == "" {
+				continue
+			}
+			extracted[f.config.Prefix+k] = v
+		}
+		if f.config.FirstMatch {
+			break
+		}
+	}
+	// A pattern can match with only empty captures, which leaves nothing to
+	// replace the source with
+	if len(extracted) == 0 {
+		return true
+	}
+
+	if f.config.DeleteSource {
+		delete(ev.Data, f.config.Field)
+	}
+	for k, v := range extracted {
+		ev.Data[k] = v
+	}
+	return true
+}
@@ -0,0 +1,93 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtract(t *testing.T) {
+	processor := &FieldExtractor{}
+	err := processor.Init(map[string]interface{}{
+		"field": "message",
+		"patterns": []interface{}{
+			`user_id=(?P<user_id__int>\d+)`,
+			`took (?P<duration_ms>[0-9.]+)ms`,
+			`cache=(?P<cache_hit>hit|miss)?`,
+		},
+		"types":  map[interface{}]interface{}{"duration_ms": "float"},
+		"prefix": "message.",
+	})
+	assert.NoError(t, err)
+
+	e := &event.Event{
+		Data: map[string]interface{}{
+			"message": "request for user_id=42 took 12.5ms cache=",
+		},
+	}
+	assert.True(t, processor.Process(e))
+	assert.Equal(t, map[string]interface{}{
+		"message":             "request for user_id=42 took 12.5ms cache=",
+		"message.user_id":     int64(42),
+		"message.duration_ms": 12.5,
+	}, e.Data)
+
+	// Unmatched and non-string values leave the event alone
+	e = &event.Event{Data: map[string]interface{}{"message": "nothing to see"}}
+	assert.True(t, processor.Process(e))
+	assert.Equal(t, map[string]interface{}{"message": "nothing to see"}, e.Data)
+
+	e = &event.Event{Data: map[string]interface{}{"message": 5}}
+	assert.True(t, processor.Process(e))
+	assert.Equal(t, map[string]interface{}{"message": 5}, e.Data)
+}
+
+func TestExtractFirstMatchAndDeleteSource(t *testing.T) {
+	processor := &FieldExtractor{}
+	err := processor.Init(map[string]interface{}{
+		"field": "message",
+		"patterns": []interface{}{
+			`^(?P<method>GET|POST) (?P<path>\S+)`,
+			`(?P<path>/\S+)`,
+		},
+		"firstMatch":   true,
+		"deleteSource": true,
+	})
+	assert.NoError(t, err)
+
+	e := &event.Event{Data: map[string]interface{}{"message": "GET /users"}}
+	assert.True(t, processor.Process(e))
+	assert.Equal(t, map[string]interface{}{"method": "GET", "path": "/users"}, e.Data)
+
+	e = &event.Event{Data: map[string]interface{}{"message": "fetching /users/1"}}
+	assert.True(t, processor.Process(e))
+	assert.Equal(t, map[string]interface{}{"path": "/users/1"}, e.Data)
+
+	// The source is kept when nothing matches
+	e = &event.Event{Data: map[string]interface{}{"message": "idle"}}
+	assert.True(t, processor.Process(e))
+	assert.Equal(t, map[string]interface{}{"message": "idle"}, e.Data)
+
+	// or when a pattern matches without capturing anything
+	processor = &FieldExtractor{}
+	assert.NoError(t, processor.Init(map[string]interface{}{
+		"field":        "message",
+		"patterns":     []interface{}{`^(?P<user>\w*)@?`},
+		"deleteSource": true,
+	}))
+	e = &event.Event{Data: map[string]interface{}{"message": "@@@"}}
+	assert.True(t, processor.Process(e))
+	assert.Equal(t, map[string]interface{}{"message": "@@@"}, e.Data)
+}
+
+func TestInvalidExtract(t *testing.T) {
+	for _, options := range []map[string]interface{}{
+		{"patterns": []interface{}{`(?P<a>.)`}},
+		{"field": "message"},
+		{"field": "message", "patterns": []interface{}{`(`}},
+		{"field": "message", "patterns": []interface{}{`(?P<a>.)`}, "types": map[interface{}]interface{}{"a": "date"}},
+	} {
+		assert.Error(t, (&FieldExtractor{}).Init(options), "%v", options)
+	}
+}
@@ -0,0 +1,111 @@
+package processors
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/config"
+	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
+	"github.com/honeycombio/honeycomb-kubernetes-agent/parsers"
+	"github.com/mitchellh/mapstructure"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	ErrParseFieldUnspecified  = errors.New("parse_field processor requires a 'field' to be set")
+	ErrParseParserUnspecified = errors.New("parse_field processor requires a 'parser' to be set")
+)
+
+// FieldParser runs a parser over the value of a single field, and merges the
+// result into the event. This is useful when a structured log line wraps
+// another format, e.g. a JSON envelope whose `msg` is a logfmt string.
+type FieldParser struct {
+	config *fieldParserConfig
+	parser parsers.Parser
+}
+
+type fieldParserConfig struct {
+	Field        string
+	Parser       interface{}
+	Prefix       string
+	DeleteSource bool
+}
+
+func (f *FieldParser) Init(options map[string]interface{}) error {
+	config := &fieldParserConfig{}
+	err := mapstructure.Decode(options, config)
+	if err != nil {
+		return err
+	}
+	if config.Field == "" {
+		return ErrParseFieldUnspecified
+	}
+	if config.Parser == nil {
+		return ErrParseParserUnspecified
+	}
+
+	f.parser, err = newParserFromOption(config.Parser)
+	if err != nil {
+		return fmt.Errorf("Error setting up parser for parse_field: %v", err)
+	}
+	f.config = config
+	return nil
+}
+
+func (f *FieldParser) Process(ev *event.Event) bool {
+	if ev.Data == nil {
+		return true
+	}
+	val, ok := ev.Data[f.config.Field]
+	if !ok {
+		return true
+	}
+	valString, ok := val.(string)
+	if !ok {
+		logrus.WithFields(logrus.Fields{
+			"key":   f.config.Field,
+			"value": val,
+			"type":  fmt.Sprintf("%T", val)}).
+			Debug("Not parsing field of non-string type")
+		return true
+	}
+
+	parsed, err := f.parser.Parse(valString)
+	if err != nil {
+		logrus.WithError(err).WithField("key", f.config.Field).
+			Debug("Failed to parse field")
+		return true
+	}
+	if parsed == nil {
+		return true
+	}
+
+	if f.config.DeleteSource {
+		delete(ev.Data, f.config.Field)
+	}
+	for k, v := range parsed {
+		ev.Data[f.config.Prefix+k] = v
+	}
+	return true
+}
+
+// newParserFromOption builds a parser out of a processor option, which may be
+// written the same way as a watcher's `parser` key. Processors are shared by
+// every file a watcher tails, so one parser sees unrelated events from many
+// files at once. Parsers that carry state between lines, such as multi-line
+// parsers, would mix those events up, so they're rejected.
+func newParserFromOption(option interface{}) (parsers.Parser, error) {
+	parserConfig, err := config.NewParserConfig(option)
+	if err != nil {
+		return nil, err
+	}
+	factory, err := parsers.NewParserFactory(parserConfig)
+	if err != nil {
+		return nil, err
+	}
+	parser := factory.New()
+	if parsers.IsStateful(parser) {
+		return nil, fmt.Errorf("the %s parser joins or remembers lines, so it can't be used to parse a field", parserConfig.Name)
+	}
+	return parser, nil
+}
@@ -0,0 +1,108 @@
+package processors
+
+import (
+	"testing"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseField(t *testing.T) {
+	processor := &FieldParser{}
+	err := processor.Init(map[string]interface{}{
+		"field":  "msg",
+		"parser": "keyval",
+		"prefix": "msg.",
+	})
+	assert.NoError(t, err)
+
+	e := &event.Event{
+		Data: map[string]interface{}{
+			"level": "info",
+			"msg":   "user=alice duration=12 cached=true",
+		},
+	}
+	cont := processor.Process(e)
+	assert.True(t, cont)
+	assert.Equal(t, map[string]interface{}{
+		"level":        "info",
+		"msg":          "user=alice duration=12 cached=true",
+		"msg.user":     "alice",
+		"msg.duration": 12,
+		"msg.cached":   true,
+	}, e.Data)
+
+	// Unparseable and non-string values leave the event alone
+	e = &event.Event{Data: map[string]interface{}{"msg": 5}}
+	assert.True(t, processor.Process(e))
+	assert.Equal(t, map[string]interface{}{"msg": 5}, e.Data)
+}
+
+func TestParseFieldWithOptionsAndDeleteSource(t *testing.T) {
+	processor := &FieldParser{}
+	err := processor.Init(map[string]interface{}{
+		"field": "msg",
+		"parser": map[interface{}]interface{}{
+			"name": "nginx",
+			"options": map[interface{}]interface{}{
+				"log_format": `$remote_addr "$request" $status`,
+			},
+		},
+		"deleteSource": true,
+	})
+	assert.NoError(t, err)
+
+	e := &event.Event{
+		Data: map[string]interface{}{
+			"msg": `10.0.0.1 "GET / HTTP/1.1" 200`,
+		},
+	}
+	assert.True(t, processor.Process(e))
+	assert.Equal(t, map[string]interface{}{
+		"remote_addr": "10.0.0.1",
+		"request":     "GET / HTTP/1.1",
+		"status":      int64(200),
+	}, e.Data)
+
+	e = &event.Event{Data: map[string]interface{}{"msg": "not an access log"}}
+	assert.True(t, processor.Process(e))
+	assert.Equal(t, map[string]interface{}{"msg": "not an access log"}, e.Data)
+}
+
+func TestParseFieldInvalidConfig(t *testing.T) {
+	processor := &FieldParser{}
+	err := processor.Init(map[string]interface{}{"parser": "json"})
+	assert.Equal(t, ErrParseFieldUnspecified, err)
+	err = processor.Init(map[string]interface{}{"field": "msg"})
+	assert.Equal(t, ErrParseParserUnspecified, err)
+	err = processor.Init(map[string]interface{}{"field": "msg", "parser": "watparser"})
+	assert.Error(t, err)
+}
+
+func TestParseFieldRejectsStatefulParsers(t *testing.T) {
+	for _, parser := range []interface{}{
+		"audit",
+		"auto",
+		"java",
+		"klog",
+		"mysql_slow",
+		"postgresql",
+		map[interface{}]interface{}{"name": "k8s-audit", "options": map[interface{}]interface{}{"mergeStages": true}},
+		[]interface{}{"json", "postgresql"},
+	} {
+		processor := &FieldParser{}
+		err := processor.Init(map[string]interface{}{"field": "msg", "parser": parser})
+		assert.Error(t, err, "%v", parser)
+	}
+
+	// The stateless variants are fine
+	for _, parser := range []interface{}{
+		map[interface{}]interface{}{"name": "csv", "options": map[interface{}]interface{}{"columns": []interface{}{"a", "b"}, "header": true}},
+		"k8s-audit",
+		[]interface{}{"json", "keyval"},
+	} {
+		processor := &FieldParser{}
+		err := processor.Init(map[string]interface{}{"field": "msg", "parser": parser})
+		assert.NoError(t, err, "%v", parser)
+	}
+}
@@ -19,6 +19,16 @@ type Processor interface {
 	Init(options map[string]interface{}) error
 }
 
+// BackgroundProcessor is implemented by processors that do work in the
+// background, such as a dynamic sampler logging its stats. Start is called
+// when the watcher using the processor starts and Stop when it stops, so
+// processors built just to validate a configuration never start.
+type BackgroundProcessor interface {
+	Processor
+	Start()
+	Stop()
+}
+
 // NewProcessorFromConfig takes a configuration map that's been unmarshalled
 // out of YAML, and tries to instantiate a corresponding processor.
 // The syntax for processor configuration is:
@@ -42,7 +52,25 @@ func NewProcessorFromConfig(config map[string]map[string]interface{}) (Processor
 	return nil, errors.New("No processor found")
 }
 
+// NewProcessor creates the named processor. If the options include an `if`
+// condition, the processor only runs on events that match it.
 func NewProcessor(name string, options map[string]interface{}) (Processor, error) {
+	var cond *condition
+	if rawCondition, ok := options["if"]; ok {
+		var err error
+		cond, err = newCondition(rawCondition)
+		if err != nil {
+			return nil, fmt.Errorf("Error in condition for %s processor: %v", name, err)
+		}
+		processorOptions := make(map[string]interface{}, len(options)-1)
+		for k, v := range options {
+			if k != "if" {
+				processorOptions[k] = v
+			}
+		}
+		options = processorOptions
+	}
+
 	var p Processor
 	switch name {
 	case "route_event":
@@ -65,9 +93,20 @@ func NewProcessor(name string, options map[string]interface{}) (Processor, error
 		p = &FieldRenamer{}
 	case "additional_fields":
 		p = &AdditionalFieldsProcessor{}
+	case "parse_field":
+		p = &FieldParser{}
+	case "extract":
+		p = &FieldExtractor{}
+	case "convert":
+		p = &FieldConverter{}
+	case "redact":
+		p = &Redactor{}
 	default:
 		return nil, fmt.Errorf("Unknown processor type %s", name)
 	}
 	err := p.Init(options)
+	if err == nil && cond != nil {
+		p = &conditionalProcessor{Processor: p, condition: cond}
+	}
 	return p, err
 }
@@ -0,0 +1,280 @@
+package processors
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
+	"github.com/mitchellh/mapstructure"
+)
+
+const (
+	defaultRedactMask       = "[REDACTED]"
+	defaultRedactCountField = "meta.redactions"
+)
+
+var (
+	ErrRedactNothingToDetect = errors.New("redact processor requires at least one detector or pattern")
+)
+
+// A redactDetector finds one kind of sensitive value. If the expression has a
+// capture group, only the first group is redacted, so that e.g. the `Bearer`
+// before a token is kept. Matches that fail validation are left alone.
+type redactDetector struct {
+	name     string
+	re       *regexp.Regexp
+	validate func(string) bool
+}
+
+// The built-in detectors, in the order they're applied. JWTs go before bearer
+// tokens so that they're reported as JWTs.
+var redactDetectors = []*redactDetector{
+	{name: "jwt", re: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)},
+	{name: "bearer_token", re: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`)},
+	{name: "aws_key", re: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{name: "aws_key", re: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`)},
+	{name: "email", re: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{name: "credit_card", re: regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`), validate: isCardNumber},
+	{name: "ip", re: regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`), validate: isIP},
+	{name: "ip", re: regexp.MustCompile(`(?i)(?:[0-9a-f]{0,4}:){2,7}[0-9a-f]{1,4}\b`), validate: isIP},
+}
+
+// Redactor replaces sensitive values in string fields, such as emails and
+// credentials in free-text log messages, with a mask or a hash.
+type Redactor struct {
+	config    *redactorConfig
+	detectors []*redactDetector
+	fields    map[string]bool
+	key       []byte
+}
+
+type redactorConfig struct {
+	Fields     []string
+	Detectors  []string
+	Patterns   []string
+	Hash       bool
+	KeyFile    string
+	KeyEnv     string
+	Mask       string
+	CountField string
+}
+
+func (r *Redactor) Init(options map[string]interface{}) error {
+	config := &redactorConfig{}
+	err := mapstructure.Decode(options, config)
+	if err != nil {
+		return err
+	}
+	if config.Mask == "" {
+		config.Mask = defaultRedactMask
+	}
+	if config.CountField == "" {
+		config.CountField = defaultRedactCountField
+	}
+
+	if _, ok := options["detectors"]; !ok {
+		r.detectors = redactDetectors
+	}
+	for _, name := range config.Detectors {
+		found := false
+		for _, d := range redactDetectors {
+			if d.name == name {
+				r.detectors = append(r.detectors, d)
+				found = true
+			}
+		}
+		if !found {
+			return fmt.Errorf("Unknown redact detector %s (expected email, credit_card, bearer_token, aws_key, jwt or ip)", name)
+		}
+	}
+	for _, pattern := range config.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("Invalid redact pattern `%s`: %v", pattern, err)
+		}
+		r.detectors = append(r.detectors, &redactDetector{name: "pattern", re: re})
+	}
+	if len(r.detectors) == 0 {
+		return ErrRedactNothingToDetect
+	}
+
+	r.key, err = loadHMACKey("redact", config.KeyFile, config.KeyEnv)
+	if err != nil {
+		return err
+	}
+
+	if len(config.Fields) > 0 {
+		r.fields = make(map[string]bool, len(config.Fields))
+		for _, field := range config.Fields {
+			r.fields[field] = true
+		}
+	}
+	r.config = config
+	return nil
+}
+
+func (r *Redactor) Process(ev *event.Event) bool {
+	if ev.Data == nil {
+		return true
+	}
+	total := 0
+	for k, v := range ev.Data {
+		if r.fields != nil && !r.fields[k] {
+			continue
+		}
+		redacted, n := r.redactValue(v)
+		if n > 0 {
+			ev.Data[k] = redacted
+			total += n
+		}
+	}
+	if total > 0 {
+		if existing, ok := ev.Data[r.config.CountField].(int); ok {
+			total += existing
+		}
+		ev.Data[r.config.CountField] = total
+	}
+	return true
+}
+
+// redactValue redacts a string, or every string inside a nested object or
+// list, such as those the json parser produces, and returns the number of
+// values replaced.
+func (r *Redactor) redactValue(v interface{}) (interface{}, int) {
+	switch typed := v.(type) {
+	case string:
+		return r.redact(typed)
+	case map[string]interface{}:
+		total := 0
+		for k, nested := range typed {
+			if redacted, n := r.redactValue(nested); n > 0 {
+				typed[k] = redacted
+				total += n
+			}
+		}
+		return typed, total
+	case []interface{}:
+		total := 0
+		for i, nested := range typed {
+			if redacted, n := r.redactValue(nested); n > 0 {
+				typed[i] = redacted
+				total += n
+			}
+		}
+		return typed, total
+	}
+	return v, 0
+}
+
+// redact returns s with every detected value replaced, and the number of
+// values replaced.
+func (r *Redactor) redact(s string) (string, int) {
+	count := 0
+	for _, d := range r.detectors {
+		matches := d.re.FindAllStringSubmatchIndex(s, -1)
+		if matches == nil {
+			continue
+		}
+		var b strings.Builder
+		last := 0
+		for _, m := range matches {
+			start, end := m[0], m[1]
+			if len(m) > 2 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			if d.validate != nil && !d.validate(s[start:end]) {
+				continue
+			}
+			b.WriteString(s[last:start])
+			b.WriteString(r.replacement(s[start:end]))
+			last = end
+			count++
+		}
+		b.WriteString(s[last:])
+		s = b.String()
+	}
+	return s, count
+}
+
+func (r *Redactor) replacement(value string) string {
+	if r.config.Hash {
+		// A short hash is enough to tell values apart without making the
+		// message much longer.
+		return fmt.Sprintf("[%x]", hashValue(r.key, value)[:8])
+	}
+	return r.config.Mask
+}
+
+// Card number prefixes and lengths, by brand. Checking these as well as the
+// Luhn digit keeps timestamps, trace IDs and the like from being taken for
+// cards, since about one in ten numbers of any length passes a Luhn check.
+var cardRanges = []struct {
+	low, high      string
+	minLen, maxLen int
+}{
+	{"4", "4", 13, 19},       // Visa
+	{"51", "55", 16, 16},     // Mastercard
+	{"2221", "2720", 16, 16}, // Mastercard
+	{"34", "34", 15, 15},     // American Express
+	{"37", "37", 15, 15},     // American Express
+	{"6011", "6011", 16, 19}, // Discover
+	{"644", "649", 16, 19},   // Discover
+	{"65", "65", 16, 19},     // Discover
+	{"300", "305", 14, 19},   // Diners Club
+	{"36", "36", 14, 19},     // Diners Club
+	{"38", "39", 16, 19},     // Diners Club
+	{"3528", "3589", 16, 19}, // JCB
+	{"62", "62", 16, 19},     // UnionPay
+	{"5018", "5018", 13, 19}, // Maestro
+	{"5020", "5020", 13, 19}, // Maestro
+	{"5038", "5038", 13, 19}, // Maestro
+	{"6304", "6304", 13, 19}, // Maestro
+	{"6759", "6759", 13, 19}, // Maestro
+	{"6761", "6763", 13, 19}, // Maestro
+}
+
+// isCardNumber returns whether a number, possibly containing spaces or
+// dashes, starts like a card number of its length and passes a Luhn check.
+func isCardNumber(number string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(number)
+	for _, r := range cardRanges {
+		if len(digits) < r.minLen || len(digits) > r.maxLen {
+			continue
+		}
+		prefix := digits[:len(r.low)]
+		if prefix >= r.low && prefix <= r.high {
+			return luhnValid(digits)
+		}
+	}
+	return false
+}
+
+// luhnValid returns whether a card number, possibly containing spaces or
+// dashes, has a valid Luhn check digit.
+func luhnValid(number string) bool {
+	sum := 0
+	digits := 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c == ' ' || c == '-' {
+			continue
+		}
+		d := int(c - '0')
+		if digits%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+	}
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
+
+func isIP(s string) bool {
+	return net.ParseIP(s) != nil
+}
@@ -6,30 +6,82 @@ import (
 	"math/rand"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	dynsampler "github.com/honeycombio/dynsampler-go"
 	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
+	"github.com/honeycombio/honeytail/sample"
 	"github.com/mitchellh/mapstructure"
+	"github.com/sirupsen/logrus"
 )
 
 type SampleType string
 
 const (
-	SampleTypeStatic  SampleType = "static"
-	SampleTypeDynamic SampleType = "dynamic"
+	SampleTypeStatic        SampleType = "static"
+	SampleTypeDynamic       SampleType = "dynamic"
+	SampleTypeDeterministic SampleType = "deterministic"
 )
 
+// The dynamic sampling algorithms from dynsampler-go. See
+// https://pkg.go.dev/github.com/honeycombio/dynsampler-go for how each works.
+const (
+	algorithmAvgSampleWithMin   = "avgsamplewithmin"
+	algorithmEMASampleRate      = "emasamplerate"
+	algorithmTotalThroughput    = "totalthroughput"
+	algorithmPerKeyThroughput   = "perkeythroughput"
+	algorithmOnlyOnce           = "onlyonce"
+	algorithmWindowedThroughput = "windowedthroughput"
+)
+
+// The options each algorithm accepts, besides type, algorithm and keys
+var algorithmOptions = map[string][]string{
+	algorithmAvgSampleWithMin:   {"rate", "windowSize", "maxKeys", "minEventsPerSec"},
+	algorithmEMASampleRate:      {"rate", "windowSize", "maxKeys", "weight", "ageOutValue", "burstMultiple", "burstDetectionDelay"},
+	algorithmTotalThroughput:    {"goalThroughputPerSec", "windowSize", "maxKeys"},
+	algorithmPerKeyThroughput:   {"goalThroughputPerSec", "windowSize", "maxKeys"},
+	algorithmOnlyOnce:           {"windowSize"},
+	algorithmWindowedThroughput: {"goalThroughputPerSec", "windowSize", "maxKeys", "updateFrequency"},
+}
+
+// How often a dynamic sampler's internals are logged
+var samplerReportInterval = time.Minute
+
 type Sampler struct {
 	config     *samplerConfig
 	dynsampler dynsampler.Sampler
+	// Makes the same decision as Refinery's deterministic sampler and the
+	// beelines do for a trace ID, so that log events are kept along with the
+	// rest of their trace.
+	deterministic *sample.DeterministicSampler
+
+	// Sample rates given out since the sampler's internals were last logged
+	mu    sync.Mutex
+	rates samplerRates
+	// Closed to stop logging the dynamic sampler's stats
+	stopReport chan struct{}
+}
+
+type samplerRates struct {
+	count, sum, min, max uint
 }
 
 type samplerConfig struct {
-	Type            SampleType
-	Rate            uint
-	Keys            []string
-	WindowSize      int
-	MinEventsPerSec int
+	Type                 SampleType
+	Algorithm            string
+	Rate                 uint
+	Keys                 []string
+	Key                  string
+	WindowSize           int
+	MinEventsPerSec      int
+	MaxKeys              int
+	GoalThroughputPerSec float64
+	Weight               float64
+	AgeOutValue          float64
+	BurstMultiple        float64
+	BurstDetectionDelay  uint
+	UpdateFrequency      int
 }
 
 func (s *Sampler) Init(options map[string]interface{}) error {
@@ -42,8 +94,17 @@ func (s *Sampler) Init(options map[string]interface{}) error {
 		// Default to static if not otherwise specified
 		config.Type = SampleTypeStatic
 	}
-	if config.Type != SampleTypeStatic && config.Type != SampleTypeDynamic {
-		return errors.New("sample type must be either 'static' or 'dynamic'")
+	if config.Type != SampleTypeStatic && config.Type != SampleTypeDynamic && config.Type != SampleTypeDeterministic {
+		return errors.New("sample type must be one of 'static', 'dynamic' or 'deterministic'")
+	}
+	if config.Type == SampleTypeDeterministic {
+		if config.Key == "" {
+			return errors.New("deterministic sampling requires a 'key' to be set")
+		}
+		s.deterministic, err = sample.NewDeterministicSampler(config.Rate)
+		if err != nil {
+			return fmt.Errorf("deterministic sampling requires a 'rate' of at least 1: %v", err)
+		}
 	}
 	if config.WindowSize == 0 {
 		// Default to 30 seconds if not otherwise specified
@@ -55,10 +116,9 @@ func (s *Sampler) Init(options map[string]interface{}) error {
 	s.config = config
 
 	if s.config.Type == SampleTypeDynamic {
-		s.dynsampler = &dynsampler.AvgSampleWithMin{
-			GoalSampleRate:    int(config.Rate),
-			ClearFrequencySec: config.WindowSize,
-			MinEventsPerSec:   config.MinEventsPerSec,
+		s.dynsampler, err = newDynSampler(config, options)
+		if err != nil {
+			return err
 		}
 		if err := s.dynsampler.Start(); err != nil {
 			return fmt.Errorf("error starting dynamic sampler: %v", err)
@@ -67,19 +127,200 @@ func (s *Sampler) Init(options map[string]interface{}) error {
 	return nil
 }
 
+// newDynSampler builds the dynamic sampler for the configured algorithm,
+// checking that only options that apply to it have been set.
+func newDynSampler(config *samplerConfig, options map[string]interface{}) (dynsampler.Sampler, error) {
+	if config.Algorithm == "" {
+		config.Algorithm = algorithmAvgSampleWithMin
+	}
+	config.Algorithm = strings.ToLower(config.Algorithm)
+	allowed, ok := algorithmOptions[config.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("unknown dynamic sampling algorithm %s (expected one of avgsamplewithmin, emasamplerate, totalthroughput, perkeythroughput, onlyonce or windowedthroughput)", config.Algorithm)
+	}
+	for option := range options {
+		switch strings.ToLower(option) {
+		case "type", "algorithm", "keys":
+			continue
+		}
+		found := false
+		for _, a := range allowed {
+			if strings.EqualFold(option, a) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("option %s doesn't apply to the %s sampling algorithm", option, config.Algorithm)
+		}
+	}
+
+	switch {
+	case config.WindowSize < 0:
+		return nil, errors.New("windowSize must be positive")
+	case config.MaxKeys < 0:
+		return nil, errors.New("maxKeys must not be negative")
+	case config.MinEventsPerSec < 0:
+		return nil, errors.New("minEventsPerSec must not be negative")
+	case config.GoalThroughputPerSec < 0:
+		return nil, errors.New("goalThroughputPerSec must not be negative")
+	case config.Weight < 0 || config.Weight >= 1:
+		return nil, errors.New("weight must be between 0 and 1")
+	case config.AgeOutValue < 0 || config.AgeOutValue >= 1:
+		return nil, errors.New("ageOutValue must be between 0 and 1")
+	case config.BurstMultiple < 0:
+		return nil, errors.New("burstMultiple must not be negative")
+	case config.UpdateFrequency < 0:
+		return nil, errors.New("updateFrequency must be positive")
+	}
+	windowSize := time.Duration(config.WindowSize) * time.Second
+
+	switch config.Algorithm {
+	case algorithmEMASampleRate:
+		return &dynsampler.EMASampleRate{
+			GoalSampleRate:             int(config.Rate),
+			AdjustmentIntervalDuration: windowSize,
+			MaxKeys:                    config.MaxKeys,
+			Weight:                     config.Weight,
+			AgeOutValue:                config.AgeOutValue,
+			BurstMultiple:              config.BurstMultiple,
+			BurstDetectionDelay:        config.BurstDetectionDelay,
+		}, nil
+	case algorithmTotalThroughput, algorithmPerKeyThroughput:
+		goal := int(config.GoalThroughputPerSec)
+		if float64(goal) != config.GoalThroughputPerSec {
+			return nil, fmt.Errorf("goalThroughputPerSec must be a whole number for the %s sampling algorithm", config.Algorithm)
+		}
+		if config.Algorithm == algorithmTotalThroughput {
+			return &dynsampler.TotalThroughput{
+				GoalThroughputPerSec:   goal,
+				ClearFrequencyDuration: windowSize,
+				MaxKeys:                config.MaxKeys,
+			}, nil
+		}
+		return &dynsampler.PerKeyThroughput{
+			PerKeyThroughputPerSec: goal,
+			ClearFrequencyDuration: windowSize,
+			MaxKeys:                config.MaxKeys,
+		}, nil
+	case algorithmOnlyOnce:
+		return &dynsampler.OnlyOnce{
+			ClearFrequencyDuration: windowSize,
+		}, nil
+	case algorithmWindowedThroughput:
+		updateFrequency := time.Second
+		if config.UpdateFrequency > 0 {
+			updateFrequency = time.Duration(config.UpdateFrequency) * time.Second
+		}
+		if windowSize < updateFrequency {
+			return nil, errors.New("windowSize must be at least updateFrequency")
+		}
+		return &dynsampler.WindowedThroughput{
+			GoalThroughputPerSec:      config.GoalThroughputPerSec,
+			UpdateFrequencyDuration:   updateFrequency,
+			LookbackFrequencyDuration: windowSize,
+			MaxKeys:                   config.MaxKeys,
+		}, nil
+	}
+	return &dynsampler.AvgSampleWithMin{
+		GoalSampleRate:         int(config.Rate),
+		ClearFrequencyDuration: windowSize,
+		MaxKeys:                config.MaxKeys,
+		MinEventsPerSec:        config.MinEventsPerSec,
+	}, nil
+}
+
 func (s *Sampler) Process(ev *event.Event) bool {
 	var rate uint
-	if s.config.Type == SampleTypeStatic {
+	switch s.config.Type {
+	case SampleTypeStatic:
 		rate = s.config.Rate
-	} else {
+	case SampleTypeDeterministic:
+		rate = s.config.Rate
+		if val, ok := ev.Data[s.config.Key]; ok {
+			ev.SampleRate = rate
+			return s.deterministic.Sample(fmt.Sprint(val))
+		}
+		// Without a key to hash, fall back to sampling at random
+	default:
 		key := makeDynSampleKey(ev, s.config.Keys)
 		rate = uint(s.dynsampler.GetSampleRate(key))
+		if rate < 1 {
+			// Some algorithms give a rate of 0 until they've seen enough
+			// traffic to calculate one
+			rate = 1
+		}
+		s.recordRate(rate)
 	}
 	ev.SampleRate = rate
 	return !shouldDrop(rate)
 
 }
 
+// recordRate keeps track of the sample rates the dynamic sampler gives out,
+// and periodically logs them along with the sampler's own metrics.
+func (s *Sampler) recordRate(rate uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := &s.rates
+	if r.count == 0 || rate < r.min {
+		r.min = rate
+	}
+	if rate > r.max {
+		r.max = rate
+	}
+	r.count++
+	r.sum += rate
+}
+
+// Start logs a dynamic sampler's internals every samplerReportInterval until
+// the sampler is stopped, whether or not any events have come through.
+func (s *Sampler) Start() {
+	if s.dynsampler == nil || s.stopReport != nil {
+		return
+	}
+	s.stopReport = make(chan struct{})
+	go s.reportStats(s.stopReport)
+}
+
+func (s *Sampler) Stop() {
+	if s.stopReport == nil {
+		return
+	}
+	close(s.stopReport)
+	s.stopReport = nil
+	s.dynsampler.Stop()
+}
+
+func (s *Sampler) reportStats(stop <-chan struct{}) {
+	ticker := time.NewTicker(samplerReportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.report()
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *Sampler) report() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fields := logrus.Fields{"algorithm": s.config.Algorithm}
+	if r := s.rates; r.count > 0 {
+		fields["minSampleRate"] = r.min
+		fields["maxSampleRate"] = r.max
+		fields["meanSampleRate"] = float64(r.sum) / float64(r.count)
+	}
+	for name, value := range s.dynsampler.GetMetrics("") {
+		fields[name] = value
+	}
+	logrus.WithFields(fields).Info("Dynamic sampler stats")
+	s.rates = samplerRates{}
+}
+
 func shouldDrop(rate uint) bool {
 	return rand.Intn(int(rate)) != 0
 }
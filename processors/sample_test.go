@@ -1,7 +1,9 @@
 package processors
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
 	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
 	"github.com/stretchr/testify/assert"
@@ -12,6 +14,114 @@ func TestSamplerCanBuildsKeysWithInts(t *testing.T) {
 		Data: map[string]interface{}{
 			"status": 200,
 		},
-	}, []string { "status"})
+	}, []string{"status"})
 	assert.Equal(t, "200", key)
 }
+
+func TestDeterministicSampling(t *testing.T) {
+	s := &Sampler{}
+	err := s.Init(map[string]interface{}{
+		"type": "deterministic",
+		"rate": 10,
+		"key":  "trace.trace_id",
+	})
+	assert.NoError(t, err)
+
+	// The first four bytes of the SHA-1 hash of this ID, as a big-endian
+	// uint32, are 299056892, below MaxUint32/10, so it's kept at a rate of 10.
+	for i := 0; i < 10; i++ {
+		ev := &event.Event{Data: map[string]interface{}{"trace.trace_id": "4bf92f3577b34da6a3ce929d0e0e4736"}}
+		assert.True(t, s.Process(ev))
+		assert.Equal(t, uint(10), ev.SampleRate)
+		ev = &event.Event{Data: map[string]interface{}{"trace.trace_id": "abc123"}}
+		assert.False(t, s.Process(ev))
+	}
+
+	keeps := func(rate int, key string) bool {
+		s := &Sampler{}
+		assert.NoError(t, s.Init(map[string]interface{}{"type": "deterministic", "rate": rate, "key": "id"}))
+		return s.Process(&event.Event{Data: map[string]interface{}{"id": key}})
+	}
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		if keeps(10, fmt.Sprintf("trace-%d", i)) {
+			kept++
+		}
+	}
+	assert.InDelta(t, 1000, kept, 100)
+
+	assert.True(t, keeps(2, "trace-1"))
+	assert.False(t, keeps(2, "trace-4"))
+	assert.True(t, keeps(1, "trace-4"))
+
+	// Events without the key are sampled at random at the same rate
+	ev := &event.Event{Data: map[string]interface{}{}}
+	s.Process(ev)
+	assert.Equal(t, uint(10), ev.SampleRate)
+
+	assert.Error(t, (&Sampler{}).Init(map[string]interface{}{"type": "deterministic", "rate": 10}))
+	assert.Error(t, (&Sampler{}).Init(map[string]interface{}{"type": "deterministic", "key": "trace.trace_id"}))
+	assert.Error(t, (&Sampler{}).Init(map[string]interface{}{"type": "deterministic", "key": "trace.trace_id", "rate": 0}))
+}
+
+func TestDynamicSamplingAlgorithms(t *testing.T) {
+	for _, options := range []map[string]interface{}{
+		{"rate": 10},
+		{"algorithm": "avgsamplewithmin", "rate": 10, "minEventsPerSec": 5, "maxKeys": 100},
+		{"algorithm": "emasamplerate", "rate": 10, "weight": 0.3, "burstMultiple": 3.0, "burstDetectionDelay": 5},
+		{"algorithm": "totalthroughput", "goalThroughputPerSec": 50},
+		{"algorithm": "perkeythroughput", "goalThroughputPerSec": 5},
+		{"algorithm": "onlyonce", "windowSize": 60},
+		{"algorithm": "windowedThroughput", "goalThroughputPerSec": 2.5, "updateFrequency": 5},
+	} {
+		options["type"] = "dynamic"
+		options["keys"] = []interface{}{"status"}
+		s := &Sampler{}
+		assert.NoError(t, s.Init(options), "%v", options)
+		for i := 0; i < 10; i++ {
+			ev := &event.Event{Data: map[string]interface{}{"status": 200}}
+			s.Process(ev)
+			assert.GreaterOrEqual(t, ev.SampleRate, uint(1), "%v", options)
+		}
+		assert.Equal(t, uint(10), s.rates.count)
+		s.dynsampler.Stop()
+	}
+}
+
+func TestInvalidDynamicSamplingOptions(t *testing.T) {
+	for _, options := range []map[string]interface{}{
+		{"algorithm": "reservoir"},
+		{"algorithm": "onlyonce", "rate": 10},
+		{"algorithm": "totalthroughput", "rate": 10},
+		{"algorithm": "emasamplerate", "minEventsPerSec": 10},
+		{"algorithm": "emasamplerate", "weight": 1.5},
+		{"algorithm": "perkeythroughput", "goalThroughputPerSec": 2.5},
+		{"algorithm": "totalthroughput", "goalThroughputPerSec": -1.0},
+		{"algorithm": "windowedthroughput", "windowSize": 5, "updateFrequency": 10},
+		{"windowSize": -5},
+	} {
+		options["type"] = "dynamic"
+		assert.Error(t, (&Sampler{}).Init(options), "%v", options)
+	}
+}
+
+func TestDynamicSamplerReportsStats(t *testing.T) {
+	defer func(interval time.Duration) { samplerReportInterval = interval }(samplerReportInterval)
+	samplerReportInterval = 10 * time.Millisecond
+
+	s := &Sampler{}
+	assert.NoError(t, s.Init(map[string]interface{}{"type": "dynamic", "rate": 10}))
+	// Nothing is logged until the watcher starts the sampler, so validating a
+	// configuration doesn't leave a reporter running
+	assert.Nil(t, s.stopReport)
+	s.Start()
+	defer s.Stop()
+
+	s.Process(&event.Event{Data: map[string]interface{}{}})
+	// The stats are reset once they've been logged
+	assert.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.rates == samplerRates{}
+	}, time.Second, 5*time.Millisecond)
+}
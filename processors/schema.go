@@ -0,0 +1,69 @@
+package processors
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
+	"github.com/mitchellh/mapstructure"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// SchemaProcessor converts the fields of each event to the types listed for
+// its dataset, the same way as the convert processor. Schemas are read from
+// a file given by the top-level schemaFile option, e.g.
+//
+//	kubernetes-logs:
+//	  status: int
+//	  duration: duration_ms
+//	  time: timestamp
+//
+// It runs after a watcher's own processors, so events sent to another dataset
+// by route_event get that dataset's schema.
+type SchemaProcessor struct {
+	conversions map[string]*conversion
+}
+
+// LoadSchemaFile reads a schema file and returns a processor that applies it.
+func LoadSchemaFile(path string) (*SchemaProcessor, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	options := make(map[string]interface{})
+	if err := yaml.Unmarshal(contents, &options); err != nil {
+		return nil, fmt.Errorf("Error parsing schema file %s: %v", path, err)
+	}
+	s := &SchemaProcessor{}
+	if err := s.Init(options); err != nil {
+		return nil, fmt.Errorf("Error in schema file %s: %v", path, err)
+	}
+	return s, nil
+}
+
+// Init takes a map from dataset names to maps of field names and types.
+func (s *SchemaProcessor) Init(options map[string]interface{}) error {
+	s.conversions = make(map[string]*conversion, len(options))
+	for dataset, rawFields := range options {
+		var fields map[string]string
+		if err := mapstructure.Decode(rawFields, &fields); err != nil {
+			return fmt.Errorf("Invalid schema for dataset %s: %v", dataset, err)
+		}
+		c, err := newConversion(fields, "")
+		if err != nil {
+			return fmt.Errorf("Invalid schema for dataset %s: %v", dataset, err)
+		}
+		s.conversions[dataset] = c
+	}
+	return nil
+}
+
+func (s *SchemaProcessor) Process(ev *event.Event) bool {
+	if ev.Data == nil {
+		return true
+	}
+	if c, ok := s.conversions[ev.Dataset]; ok {
+		c.apply(ev)
+	}
+	return true
+}
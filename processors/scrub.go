@@ -1,19 +1,42 @@
 package processors
 
 import (
+	"crypto/hmac"
 	"crypto/sha256"
+	"errors"
 	"fmt"
+	"hash"
+	"os"
+	"regexp"
+	"strings"
 
 	"github.com/honeycombio/honeycomb-kubernetes-agent/event"
 	"github.com/mitchellh/mapstructure"
 )
 
+var (
+	ErrScrubFieldUnspecified = errors.New("scrub_field processor requires a 'field' or 'fields' to be set")
+)
+
+// FieldScrubber replaces fields with a one-way hash. By default this is a
+// plain SHA-256 hash, but values from a small set, like user IDs, can be
+// recovered from that by hashing every possible value, so a secret key can be
+// given to use HMAC-SHA256 instead.
 type FieldScrubber struct {
-	config *fieldScrubberConfig
+	config   *fieldScrubberConfig
+	fields   map[string]bool
+	patterns []*regexp.Regexp
+	key      []byte
 }
 
 type fieldScrubberConfig struct {
-	Field string
+	Field  string
+	Fields []string
+	// The key is read from a file, such as a mounted Secret, or from an
+	// environment variable.
+	KeyFile        string
+	KeyEnv         string
+	PreserveFormat bool
 }
 
 func (f *FieldScrubber) Init(options map[string]interface{}) error {
@@ -22,15 +45,144 @@ func (f *FieldScrubber) Init(options map[string]interface{}) error {
 	if err != nil {
 		return err
 	}
+
+	names := config.Fields
+	if config.Field != "" {
+		names = append([]string{config.Field}, names...)
+	}
+	if len(names) == 0 {
+		return ErrScrubFieldUnspecified
+	}
+	f.fields = make(map[string]bool)
+	for _, name := range names {
+		if !strings.ContainsAny(name, `*?[\`) {
+			f.fields[name] = true
+			continue
+		}
+		pattern, err := compileFieldPattern(name)
+		if err != nil {
+			return fmt.Errorf("Invalid field pattern %s for scrub_field: %v", name, err)
+		}
+		f.patterns = append(f.patterns, pattern)
+	}
+
+	f.key, err = loadHMACKey("scrub_field", config.KeyFile, config.KeyEnv)
+	if err != nil {
+		return err
+	}
+
 	f.config = config
 	return nil
 }
 
 func (f *FieldScrubber) Process(ev *event.Event) bool {
-	if val, ok := ev.Data[f.config.Field]; ok {
-		// generate a sha256 hash and use the base16 for the content
-		newVal := sha256.Sum256([]byte(fmt.Sprintf("%v", val)))
-		ev.Data[f.config.Field] = fmt.Sprintf("%x", newVal)
+	for k, val := range ev.Data {
+		if f.matches(k) {
+			ev.Data[k] = f.scrub(fmt.Sprintf("%v", val))
+		}
 	}
 	return true
 }
+
+// compileFieldPattern turns a glob like request.headers.* into a regexp. Unlike
+// path.Match, * and ? also match /, which turns up in field names such as
+// annotations.authorization.k8s.io/decision.
+func compileFieldPattern(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '\\':
+			if i+1 == len(glob) {
+				return nil, errors.New("trailing backslash")
+			}
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return nil, errors.New("unterminated character class")
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
+
+func (f *FieldScrubber) matches(field string) bool {
+	if f.fields[field] {
+		return true
+	}
+	for _, pattern := range f.patterns {
+		if pattern.MatchString(field) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *FieldScrubber) scrub(val string) string {
+	if f.config.PreserveFormat {
+		// Keep the domain of an email address, so that e.g. internal and
+		// external users can still be told apart.
+		if at := strings.LastIndexByte(val, '@'); at > 0 && at < len(val)-1 {
+			return f.hash(val[:at]) + val[at:]
+		}
+	}
+	return f.hash(val)
+}
+
+func (f *FieldScrubber) hash(val string) string {
+	// use the base16 for the content
+	return fmt.Sprintf("%x", hashValue(f.key, val))
+}
+
+// loadHMACKey reads a processor's secret key from a file, such as a mounted
+// Secret, or from an environment variable. It returns nil if neither is set.
+func loadHMACKey(processor, keyFile, keyEnv string) ([]byte, error) {
+	var key string
+	switch {
+	case keyFile != "" && keyEnv != "":
+		return nil, fmt.Errorf("%s processor accepts only one of 'keyFile' and 'keyEnv'", processor)
+	case keyFile != "":
+		contents, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading %s key: %v", processor, err)
+		}
+		// Secrets are liable to end up with a trailing newline
+		key = strings.TrimSpace(string(contents))
+	case keyEnv != "":
+		key = strings.TrimSpace(os.Getenv(keyEnv))
+	default:
+		return nil, nil
+	}
+	if key == "" {
+		return nil, fmt.Errorf("%s key is empty", processor)
+	}
+	return []byte(key), nil
+}
+
+// hashValue returns the HMAC-SHA256 of val with key, or if there's no key,
+// its plain SHA-256 hash.
+func hashValue(key []byte, val string) []byte {
+	var h hash.Hash
+	if key != nil {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = sha256.New()
+	}
+	h.Write([]byte(val))
+	return h.Sum(nil)
+}
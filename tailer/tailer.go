@@ -150,6 +150,9 @@ func NewPathWatcher(
 }
 
 func (p *PathWatcher) Start() {
+	if hf, ok := p.handlerFactory.(handlers.BackgroundLineHandlerFactory); ok {
+		hf.Start()
+	}
 	go p.run()
 }
 
@@ -170,6 +173,9 @@ func (p *PathWatcher) Stop() {
 	for _, tailer := range p.tailers {
 		tailer.Stop()
 	}
+	if hf, ok := p.handlerFactory.(handlers.BackgroundLineHandlerFactory); ok {
+		hf.Stop()
+	}
 }
 
 // This emulates filepath.Glob's behavior using doublestar,